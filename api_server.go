@@ -1,7 +1,10 @@
 package dockervolume
 
 import (
-	"fmt"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -15,180 +18,788 @@ import (
 	"golang.org/x/net/context"
 )
 
+const (
+	// statusErrorKey is the only key of the status of a volume when the
+	// VolumeDriverStatusReporter fails.
+	statusErrorKey = "error"
+	// eventBufferSize is the number of events a watcher can fall behind
+	// before it is disconnected.
+	eventBufferSize = 1024
+)
+
 type apiServer struct {
 	protorpclog.Logger
-	volumeDriver     VolumeDriver
-	volumeDriverName string
-	nameToVolume     map[string]*Volume
-	lock             *sync.RWMutex
+	volumeDriver        VolumeDriver
+	contextVolumeDriver ContextVolumeDriver
+	volumeDriverName    string
+	volumeStore         VolumeStore
+	capabilities        *Capabilities
+	dockerClient        DockerClient
+	createTimeout       time.Duration
+	removeTimeout       time.Duration
+	mountTimeout        time.Duration
+	unmountTimeout      time.Duration
+	statusTimeout       time.Duration
+	volumeLocker        *nameLocker
+	eventBroadcaster    *eventBroadcaster
+	// nameToVolume is guarded by lock, and the Volumes in it are never
+	// modified, they are replaced. Operations on a single volume are
+	// serialized by volumeLocker, so that driver calls for different volumes
+	// can happen at the same time.
+	nameToVolume map[string]*Volume
+	lock         *sync.RWMutex
 }
 
-func newAPIServer(volumeDriver VolumeDriver, volumeDriverName string) *apiServer {
-	return &apiServer{
+func newAPIServer(volumeDriver VolumeDriver, volumeDriverName string, opts APIServerOptions) (*apiServer, error) {
+	volumeStore := opts.VolumeStore
+	if volumeStore == nil {
+		volumeStore = newMemoryVolumeStore()
+	}
+	capabilities := &Capabilities{
+		Scope: ScopeLocal,
+	}
+	if opts.Capabilities != nil {
+		capabilities = copyCapabilities(opts.Capabilities)
+		if capabilities.Scope == "" {
+			capabilities.Scope = ScopeLocal
+		}
+	}
+	if err := validateScope(capabilities.Scope); err != nil {
+		return nil, err
+	}
+	volumes, err := volumeStore.List()
+	if err != nil {
+		return nil, err
+	}
+	nameToVolume := make(map[string]*Volume)
+	for _, volume := range volumes {
+		nameToVolume[volume.Name] = volume
+	}
+	server := &apiServer{
 		protorpclog.NewLogger("dockervolume.API"),
 		volumeDriver,
+		newContextVolumeDriver(volumeDriver),
 		volumeDriverName,
-		make(map[string]*Volume),
+		volumeStore,
+		capabilities,
+		opts.DockerClient,
+		opts.CreateTimeout,
+		opts.RemoveTimeout,
+		opts.MountTimeout,
+		opts.UnmountTimeout,
+		opts.StatusTimeout,
+		newNameLocker(),
+		newEventBroadcaster(eventBufferSize),
+		nameToVolume,
 		&sync.RWMutex{},
 	}
+	if opts.ReconcileOnStart != nil {
+		if _, err := server.Reconcile(context.Background(), opts.ReconcileOnStart); err != nil {
+			return nil, err
+		}
+	}
+	return server, nil
 }
 
-func (a *apiServer) Create(_ context.Context, request *NameOptsRequest) (response *ErrResponse, err error) {
+func (a *apiServer) Create(ctx context.Context, request *NameOptsRequest) (response *ErrResponse, err error) {
 	defer func(start time.Time) { a.Log(request, response, err, time.Since(start)) }(time.Now())
-	return doNameOptsToErr(request, a.create)
+	return doNameOptsToErr(ctx, request, a.create)
 }
 
-func (a *apiServer) create(name string, opts map[string]string) error {
+func (a *apiServer) create(ctx context.Context, name string, opts map[string]string) error {
 	volume := &Volume{
-		name,
-		opts,
-		"",
+		Name:      name,
+		Opts:      opts,
+		CreatedAt: timeToTimestamp(time.Now()),
 	}
-	a.lock.Lock()
-	defer a.lock.Unlock()
-	if _, ok := a.nameToVolume[name]; ok {
-		return fmt.Errorf("dockervolume: volume already created: %s", name)
+	a.volumeLocker.Lock(name)
+	defer a.volumeLocker.Unlock(name)
+	if _, ok := a.getVolume(name); ok {
+		return newVolumeError("create", name, ErrVolumeExists)
 	}
-	if err := a.volumeDriver.Create(name, pkgmap.StringStringMap(opts)); err != nil {
+	ctx, cancel := withTimeout(ctx, a.createTimeout)
+	defer cancel()
+	if err := a.contextVolumeDriver.CreateContext(ctx, name, pkgmap.StringStringMap(opts)); err != nil {
+		err = newDriverError("create", name, err)
+		a.publish(EventType_EVENT_TYPE_CREATE, volume, "", err)
 		return err
 	}
-	a.nameToVolume[name] = volume
+	if err := a.putVolume(volume); err != nil {
+		// a volume created by the volume driver that is not known is removed again
+		ctx, cancel := withTimeout(context.Background(), a.removeTimeout)
+		defer cancel()
+		_ = a.contextVolumeDriver.RemoveContext(ctx, name, pkgmap.StringStringMap(opts).Copy(), "")
+		return err
+	}
+	a.publish(EventType_EVENT_TYPE_CREATE, volume, "", nil)
 	return nil
 }
 
-func (a *apiServer) Remove(_ context.Context, request *NameRequest) (response *ErrResponse, err error) {
+func (a *apiServer) Remove(ctx context.Context, request *NameRequest) (response *ErrResponse, err error) {
 	defer func(start time.Time) { a.Log(request, response, err, time.Since(start)) }(time.Now())
-	return doNameToErr(request, a.remove)
+	return doNameToErr(ctx, request, a.remove)
 }
 
-func (a *apiServer) remove(name string) error {
-	a.lock.Lock()
-	defer a.lock.Unlock()
-	volume, ok := a.nameToVolume[name]
+func (a *apiServer) remove(ctx context.Context, name string) error {
+	a.volumeLocker.Lock(name)
+	defer a.volumeLocker.Unlock(name)
+	volume, ok := a.getVolume(name)
 	if !ok {
-		return fmt.Errorf("dockervolume: volume does not exist: %s", name)
+		return newVolumeError("remove", name, ErrVolumeNotFound)
 	}
-	delete(a.nameToVolume, name)
-	return a.volumeDriver.Remove(volume.Name, pkgmap.StringStringMap(volume.Opts).Copy(), volume.Mountpoint)
+	if len(volume.MountIds) > 0 {
+		return newVolumeError("remove", name, ErrAlreadyMounted)
+	}
+	if err := a.deleteVolume(name); err != nil {
+		return err
+	}
+	ctx, cancel := withTimeout(ctx, a.removeTimeout)
+	defer cancel()
+	err := newDriverError(
+		"remove",
+		name,
+		a.contextVolumeDriver.RemoveContext(ctx, volume.Name, pkgmap.StringStringMap(volume.Opts).Copy(), volume.Mountpoint),
+	)
+	a.publish(EventType_EVENT_TYPE_REMOVE, volume, "", err)
+	return err
 }
 
-func (a *apiServer) Path(_ context.Context, request *NameRequest) (response *MountpointErrResponse, err error) {
+func (a *apiServer) Path(ctx context.Context, request *NameRequest) (response *MountpointErrResponse, err error) {
 	defer func(start time.Time) { a.Log(request, response, err, time.Since(start)) }(time.Now())
-	return doNameToMountpointErr(request, a.path)
+	return doNameToMountpointErr(ctx, request, a.path)
 }
 
-func (a *apiServer) path(name string) (string, error) {
-	a.lock.RLock()
-	defer a.lock.RUnlock()
-	volume, ok := a.nameToVolume[name]
+func (a *apiServer) path(_ context.Context, name string) (string, error) {
+	volume, ok := a.getVolume(name)
 	if !ok {
-		return "", fmt.Errorf("dockervolume: volume does not exist: %s", name)
+		return "", newVolumeError("path", name, ErrVolumeNotFound)
 	}
 	return volume.Mountpoint, nil
 }
 
-func (a *apiServer) Mount(_ context.Context, request *NameRequest) (response *MountpointErrResponse, err error) {
+func (a *apiServer) Mount(ctx context.Context, request *NameIDRequest) (response *MountpointErrResponse, err error) {
 	defer func(start time.Time) { a.Log(request, response, err, time.Since(start)) }(time.Now())
-	return doNameToMountpointErr(request, a.mount)
+	return doNameIDToMountpointErr(ctx, request, a.mount)
 }
 
-func (a *apiServer) mount(name string) (string, error) {
-	a.lock.Lock()
-	defer a.lock.Unlock()
-	volume, ok := a.nameToVolume[name]
+func (a *apiServer) mount(ctx context.Context, name string, id string) (string, error) {
+	a.volumeLocker.Lock(name)
+	defer a.volumeLocker.Unlock(name)
+	volume, ok := a.getVolume(name)
 	if !ok {
-		return "", fmt.Errorf("dockervolume: volume does not exist: %s", name)
+		return "", newVolumeError("mount", name, ErrVolumeNotFound)
+	}
+	// callers without an id, as older versions of docker, are counted separately
+	if id != "" && containsString(volume.MountIds, id) {
+		return volume.Mountpoint, nil
+	}
+	// only the first caller actually mounts the volume, every other caller shares the mountpoint
+	if len(volume.MountIds) == 0 {
+		ctx, cancel := withTimeout(ctx, a.mountTimeout)
+		defer cancel()
+		mountpoint, err := a.contextVolumeDriver.MountContext(ctx, volume.Name, pkgmap.StringStringMap(volume.Opts).Copy())
+		if err != nil {
+			err = newDriverError("mount", name, err)
+			a.publish(EventType_EVENT_TYPE_MOUNT, volume, id, err)
+			return "", err
+		}
+		volume.Mountpoint = mountpoint
 	}
-	if volume.Mountpoint != "" {
-		return "", fmt.Errorf("dockervolume: volume already mounted: %s at %s", volume.Name, volume.Mountpoint)
+	volume.MountIds = append(volume.MountIds, id)
+	if err := a.putVolume(volume); err != nil {
+		// a mount by the volume driver that is not known is unmounted again
+		if len(volume.MountIds) == 1 {
+			ctx, cancel := withTimeout(context.Background(), a.unmountTimeout)
+			defer cancel()
+			_ = a.contextVolumeDriver.UnmountContext(ctx, volume.Name, pkgmap.StringStringMap(volume.Opts).Copy(), volume.Mountpoint)
+		}
+		return "", err
 	}
-	mountpoint, err := a.volumeDriver.Mount(volume.Name, pkgmap.StringStringMap(volume.Opts).Copy())
-	volume.Mountpoint = mountpoint
-	return mountpoint, err
+	a.publish(EventType_EVENT_TYPE_MOUNT, volume, id, nil)
+	return volume.Mountpoint, nil
 }
 
-func (a *apiServer) Unmount(_ context.Context, request *NameRequest) (response *ErrResponse, err error) {
+func (a *apiServer) Unmount(ctx context.Context, request *NameIDRequest) (response *ErrResponse, err error) {
 	defer func(start time.Time) { a.Log(request, response, err, time.Since(start)) }(time.Now())
-	return doNameToErr(request, a.unmount)
+	return doNameIDToErr(ctx, request, a.unmount)
 }
 
-func (a *apiServer) unmount(name string) error {
-	a.lock.Lock()
-	defer a.lock.Unlock()
-	volume, ok := a.nameToVolume[name]
+func (a *apiServer) unmount(ctx context.Context, name string, id string) error {
+	a.volumeLocker.Lock(name)
+	defer a.volumeLocker.Unlock(name)
+	volume, ok := a.getVolume(name)
 	if !ok {
-		return fmt.Errorf("dockervolume: volume does not exist: %s", name)
+		return newVolumeError("unmount", name, ErrVolumeNotFound)
 	}
-	if volume.Mountpoint == "" {
-		return fmt.Errorf("dockervolume: volume not mounted: %s at %s", volume.Name, volume.Mountpoint)
+	if !containsString(volume.MountIds, id) {
+		return newVolumeError("unmount", name, ErrNotMounted)
 	}
-	mountpoint := volume.Mountpoint
-	volume.Mountpoint = ""
-	return a.volumeDriver.Unmount(volume.Name, pkgmap.StringStringMap(volume.Opts).Copy(), mountpoint)
+	// only the last caller actually unmounts the volume, and the volume stays
+	// mounted by the caller until the volume driver has unmounted it
+	if len(volume.MountIds) == 1 {
+		ctx, cancel := withTimeout(ctx, a.unmountTimeout)
+		defer cancel()
+		if err := a.contextVolumeDriver.UnmountContext(ctx, volume.Name, pkgmap.StringStringMap(volume.Opts).Copy(), volume.Mountpoint); err != nil {
+			err = newDriverError("unmount", name, err)
+			a.publish(EventType_EVENT_TYPE_UNMOUNT, volume, id, err)
+			return err
+		}
+		volume.Mountpoint = ""
+	}
+	volume.MountIds = removeString(volume.MountIds, id)
+	if err := a.putVolume(volume); err != nil {
+		return err
+	}
+	a.publish(EventType_EVENT_TYPE_UNMOUNT, volume, id, nil)
+	return nil
+}
+
+func (a *apiServer) Get(ctx context.Context, request *NameRequest) (response *VolumeErrResponse, err error) {
+	defer func(start time.Time) { a.Log(request, response, err, time.Since(start)) }(time.Now())
+	return doNameToVolumeErr(ctx, request, a.get)
+}
+
+func (a *apiServer) get(ctx context.Context, name string) (*Volume, error) {
+	a.volumeLocker.Lock(name)
+	defer a.volumeLocker.Unlock(name)
+	volume, ok := a.getVolume(name)
+	if !ok {
+		return nil, newVolumeError("get", name, ErrVolumeNotFound)
+	}
+	ctx, cancel := withTimeout(ctx, a.statusTimeout)
+	defer cancel()
+	return a.describeVolume(ctx, volume), nil
+}
+
+func (a *apiServer) List(_ context.Context, request *google_protobuf.Empty) (response *VolumesErrResponse, err error) {
+	defer func(start time.Time) { a.Log(request, response, err, time.Since(start)) }(time.Now())
+	return &VolumesErrResponse{
+		Volumes: toPluginVolumes(a.list()),
+	}, nil
+}
+
+// list returns a copy of every volume, sorted by name.
+func (a *apiServer) list() []*Volume {
+	a.lock.RLock()
+	volumes := make([]*Volume, len(a.nameToVolume))
+	i := 0
+	for _, volume := range a.nameToVolume {
+		volumes[i] = copyVolume(volume)
+		i++
+	}
+	a.lock.RUnlock()
+	sort.Sort(volumesByName(volumes))
+	return volumes
 }
 
-func (a *apiServer) Cleanup(_ context.Context, request *google_protobuf.Empty) (response *Volumes, err error) {
+func (a *apiServer) Capabilities(_ context.Context, request *google_protobuf.Empty) (response *CapabilitiesResponse, err error) {
 	defer func(start time.Time) { a.Log(request, response, err, time.Since(start)) }(time.Now())
-	client, err := docker.NewClientFromEnv()
+	return &CapabilitiesResponse{
+		Capabilities: copyCapabilities(a.capabilities),
+	}, nil
+}
+
+func (a *apiServer) Cleanup(ctx context.Context, request *CleanupRequest) (response *Volumes, err error) {
+	defer func(start time.Time) { a.Log(request, response, err, time.Since(start)) }(time.Now())
+	filter, err := newVolumeFilter(
+		&ListVolumesRequest{
+			NamePrefix: request.NamePrefix,
+			Selector:   request.Selector,
+		},
+	)
 	if err != nil {
-		return nil, err
+		return nil, grpc.Errorf(codes.InvalidArgument, "%s", err.Error())
 	}
-	allVolumes, err := client.ListVolumes(docker.ListVolumesOptions{})
+	client, err := a.getDockerClient()
 	if err != nil {
-		return nil, err
+		return nil, toGRPCError(err)
 	}
-	var driverVolumes []docker.Volume
-	for _, volume := range allVolumes {
-		if volume.Driver == a.volumeDriverName {
-			driverVolumes = append(driverVolumes, volume)
-		}
+	dockerVolumeNames, err := a.getDockerVolumeNames(client)
+	if err != nil {
+		return nil, toGRPCError(err)
 	}
-	var volumes []*Volume
-	a.lock.RLock()
-	for _, dockerVolume := range driverVolumes {
-		if volume, ok := a.nameToVolume[dockerVolume.Name]; ok {
-			volumes = append(volumes, copyVolume(volume))
+	var usedVolumeNames []string
+	if request.OnlyUnused {
+		usedVolumeNames, err = a.getUsedVolumeNames(client)
+		if err != nil {
+			return nil, toGRPCError(err)
 		}
 	}
-	a.lock.RUnlock()
+	volumes := a.cleanupCandidates(dockerVolumeNames, usedVolumeNames, filter)
+	if request.DryRun {
+		return &Volumes{
+			Volume: volumes,
+		}, nil
+	}
 	var errs []error
 	for _, volume := range volumes {
-		if err := client.RemoveVolume(volume.Name); err != nil {
+		var err error
+		if request.Force {
+			err = a.unmountAll(ctx, volume)
+		}
+		if err == nil {
+			err = client.RemoveVolume(volume.Name)
+		}
+		if err != nil {
 			errs = append(errs, err)
 		}
+		a.publish(EventType_EVENT_TYPE_CLEANUP, volume, "", err)
 	}
+	// only the first error is returned, the errors of all volumes are
+	// published as events
 	if len(errs) > 0 {
-		err = grpc.Errorf(codes.Internal, "%v", errs)
+		err = toGRPCError(errs[0])
 	}
 	return &Volumes{
 		Volume: volumes,
 	}, err
 }
 
-func (a *apiServer) GetVolume(_ context.Context, request *NameRequest) (response *Volume, err error) {
+// cleanupCandidates returns the volumes that docker knows about, that are not
+// used by a container, and that match the filter, sorted by name.
+func (a *apiServer) cleanupCandidates(dockerVolumeNames []string, usedVolumeNames []string, filter *volumeFilter) []*Volume {
+	dockerNames := toStringSet(dockerVolumeNames)
+	usedNames := toStringSet(usedVolumeNames)
+	var volumes []*Volume
+	for _, volume := range a.list() {
+		if dockerNames[volume.Name] && !usedNames[volume.Name] && filter.matches(volume) {
+			volumes = append(volumes, volume)
+		}
+	}
+	return volumes
+}
+
+// unmountAll unmounts the given volume for every caller that has it mounted.
+func (a *apiServer) unmountAll(ctx context.Context, volume *Volume) error {
+	for _, id := range volume.MountIds {
+		if err := a.unmount(ctx, volume.Name, id); err != nil && !errors.Is(err, ErrNotMounted) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *apiServer) getDockerClient() (DockerClient, error) {
+	if a.dockerClient != nil {
+		return a.dockerClient, nil
+	}
+	return docker.NewClientFromEnv()
+}
+
+// getDockerVolumeNames returns the names of the volumes of this volume driver
+// that docker knows about.
+func (a *apiServer) getDockerVolumeNames(client DockerClient) ([]string, error) {
+	dockerVolumes, err := client.ListVolumes(docker.ListVolumesOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, dockerVolume := range dockerVolumes {
+		if dockerVolume.Driver == a.volumeDriverName {
+			names = append(names, dockerVolume.Name)
+		}
+	}
+	return names, nil
+}
+
+// getUsedVolumeNames returns the names of the volumes of this volume driver
+// that are referenced by a container, running or not.
+func (a *apiServer) getUsedVolumeNames(client DockerClient) ([]string, error) {
+	containers, err := client.ListContainers(docker.ListContainersOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, container := range containers {
+		for _, mount := range container.Mounts {
+			if mount.Driver == a.volumeDriverName {
+				names = append(names, mount.Name)
+			}
+		}
+	}
+	return names, nil
+}
+
+func (a *apiServer) GetVolume(ctx context.Context, request *NameRequest) (response *Volume, err error) {
 	defer func(start time.Time) { a.Log(request, response, err, time.Since(start)) }(time.Now())
-	a.lock.RLock()
-	defer a.lock.RUnlock()
-	volume, ok := a.nameToVolume[request.Name]
+	volume, err := a.get(ctx, request.Name)
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+	return volume, nil
+}
+
+func (a *apiServer) ListVolumes(_ context.Context, request *ListVolumesRequest) (response *Volumes, err error) {
+	defer func(start time.Time) { a.Log(request, response, err, time.Since(start)) }(time.Now())
+	volumes, nextPageToken, err := a.listVolumes(request)
+	if err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "%s", err.Error())
+	}
+	return &Volumes{
+		Volume:        volumes,
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+func (a *apiServer) listVolumes(request *ListVolumesRequest) ([]*Volume, string, error) {
+	filter, err := newVolumeFilter(request)
+	if err != nil {
+		return nil, "", err
+	}
+	var after string
+	if request.PageToken != "" {
+		after, err = decodePageToken(request.PageToken)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	var volumes []*Volume
+	for _, volume := range a.list() {
+		if request.PageToken != "" && volume.Name <= after {
+			continue
+		}
+		if !filter.matches(volume) {
+			continue
+		}
+		if request.PageSize > 0 && len(volumes) == int(request.PageSize) {
+			return volumes, encodePageToken(volumes[len(volumes)-1].Name), nil
+		}
+		volumes = append(volumes, volume)
+	}
+	return volumes, "", nil
+}
+
+func (a *apiServer) Reconcile(_ context.Context, request *ReconcileRequest) (response *ReconcileResponse, err error) {
+	defer func(start time.Time) { a.Log(request, response, err, time.Since(start)) }(time.Now())
+	client, err := a.getDockerClient()
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+	dockerVolumeNames, err := a.getDockerVolumeNames(client)
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+	response, err = a.reconcile(dockerVolumeNames, request.Repair)
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+	return response, nil
+}
+
+// reconcile compares the volumes managed by the API with the given names of
+// the volumes docker knows about, and with the volumes of the volume driver
+// if it implements VolumeDriverLister.
+func (a *apiServer) reconcile(dockerVolumeNames []string, repair bool) (*ReconcileResponse, error) {
+	dockerNames := toStringSet(dockerVolumeNames)
+	var driverNames map[string]bool
+	if volumeDriverLister, ok := a.volumeDriver.(VolumeDriverLister); ok {
+		driverVolumeNames, err := volumeDriverLister.ListVolumeNames()
+		if err != nil {
+			return nil, &DriverError{err}
+		}
+		driverNames = toStringSet(driverVolumeNames)
+	}
+	response := &ReconcileResponse{}
+	volumes := a.list()
+	apiNames := make(map[string]bool, len(volumes))
+	for _, volume := range volumes {
+		name := volume.Name
+		apiNames[name] = true
+		if !dockerNames[name] {
+			response.MissingInDocker = append(response.MissingInDocker, name)
+		}
+		if driverNames != nil && !driverNames[name] {
+			response.MissingInDriver = append(response.MissingInDriver, name)
+			if repair {
+				repaired, err := a.forgetVolume(name)
+				if err != nil {
+					return nil, err
+				}
+				if repaired {
+					response.Repaired = append(response.Repaired, name)
+				}
+			}
+			continue
+		}
+		if volume.Mountpoint != "" && !pathExists(volume.Mountpoint) {
+			response.StaleMountpoints = append(response.StaleMountpoints, name)
+			if repair {
+				repaired, err := a.clearMountpoint(name, volume.Mountpoint)
+				if err != nil {
+					return nil, err
+				}
+				if repaired {
+					response.Repaired = append(response.Repaired, name)
+				}
+			}
+		}
+	}
+	for name := range dockerNames {
+		if apiNames[name] {
+			continue
+		}
+		response.MissingInApi = append(response.MissingInApi, name)
+		// only manage volumes again that still exist in the volume driver
+		if repair && (driverNames == nil || driverNames[name]) {
+			repaired, err := a.adoptVolume(name)
+			if err != nil {
+				return nil, err
+			}
+			if repaired {
+				response.Repaired = append(response.Repaired, name)
+			}
+		}
+	}
+	for name := range driverNames {
+		if !apiNames[name] && !dockerNames[name] {
+			response.DriverOrphans = append(response.DriverOrphans, name)
+		}
+	}
+	sort.Strings(response.MissingInDocker)
+	sort.Strings(response.MissingInApi)
+	sort.Strings(response.MissingInDriver)
+	sort.Strings(response.DriverOrphans)
+	sort.Strings(response.StaleMountpoints)
+	sort.Strings(response.Repaired)
+	return response, nil
+}
+
+func (a *apiServer) ListDriverVolumes(_ context.Context, request *google_protobuf.Empty) (response *Names, err error) {
+	defer func(start time.Time) { a.Log(request, response, err, time.Since(start)) }(time.Now())
+	volumeDriverLister, ok := a.volumeDriver.(VolumeDriverLister)
+	if !ok {
+		return nil, toGRPCError(ErrUnimplemented)
+	}
+	names, err := volumeDriverLister.ListVolumeNames()
+	if err != nil {
+		return nil, toGRPCError(&DriverError{err})
+	}
+	sort.Strings(names)
+	return &Names{
+		Name: names,
+	}, nil
+}
+
+func (a *apiServer) GetVolumeStatus(_ context.Context, request *NameRequest) (response *VolumeStatus, err error) {
+	defer func(start time.Time) { a.Log(request, response, err, time.Since(start)) }(time.Now())
+	status, err := a.status(request.Name)
+	if err != nil {
+		return nil, toGRPCError(err)
+	}
+	return &VolumeStatus{
+		Status: status,
+	}, nil
+}
+
+func (a *apiServer) status(name string) (map[string]string, error) {
+	volumeDriverStatusReporter, ok := a.volumeDriver.(VolumeDriverStatusReporter)
 	if !ok {
-		return nil, grpc.Errorf(codes.NotFound, request.Name)
+		return nil, newVolumeError("status", name, ErrUnimplemented)
 	}
-	return copyVolume(volume), nil
+	a.volumeLocker.Lock(name)
+	defer a.volumeLocker.Unlock(name)
+	volume, ok := a.getVolume(name)
+	if !ok {
+		return nil, newVolumeError("status", name, ErrVolumeNotFound)
+	}
+	status, err := volumeDriverStatusReporter.Status(volume.Name, pkgmap.StringStringMap(volume.Opts).Copy(), volume.Mountpoint)
+	if err != nil {
+		return nil, newDriverError("status", name, err)
+	}
+	return status, nil
+}
+
+func (a *apiServer) SnapshotVolume(_ context.Context, request *SnapshotRequest) (response *google_protobuf.Empty, err error) {
+	defer func(start time.Time) { a.Log(request, response, err, time.Since(start)) }(time.Now())
+	if err := a.snapshot(request.Name, request.SnapshotName); err != nil {
+		return nil, toGRPCError(err)
+	}
+	return google_protobuf.EmptyInstance, nil
+}
+
+func (a *apiServer) snapshot(name string, snapshotName string) error {
+	volumeDriverSnapshotter, ok := a.volumeDriver.(VolumeDriverSnapshotter)
+	if !ok {
+		return newVolumeError("snapshot", name, ErrUnimplemented)
+	}
+	a.volumeLocker.Lock(name)
+	defer a.volumeLocker.Unlock(name)
+	volume, ok := a.getVolume(name)
+	if !ok {
+		return newVolumeError("snapshot", name, ErrVolumeNotFound)
+	}
+	return newDriverError(
+		"snapshot",
+		name,
+		volumeDriverSnapshotter.Snapshot(volume.Name, pkgmap.StringStringMap(volume.Opts).Copy(), volume.Mountpoint, snapshotName),
+	)
 }
 
-func (a *apiServer) ListVolumes(_ context.Context, request *google_protobuf.Empty) (response *Volumes, err error) {
+func (a *apiServer) ResizeVolume(_ context.Context, request *ResizeRequest) (response *google_protobuf.Empty, err error) {
 	defer func(start time.Time) { a.Log(request, response, err, time.Since(start)) }(time.Now())
+	if err := a.resize(request.Name, request.SizeBytes); err != nil {
+		return nil, toGRPCError(err)
+	}
+	return google_protobuf.EmptyInstance, nil
+}
+
+func (a *apiServer) resize(name string, sizeBytes uint64) error {
+	volumeDriverResizer, ok := a.volumeDriver.(VolumeDriverResizer)
+	if !ok {
+		return newVolumeError("resize", name, ErrUnimplemented)
+	}
+	a.volumeLocker.Lock(name)
+	defer a.volumeLocker.Unlock(name)
+	volume, ok := a.getVolume(name)
+	if !ok {
+		return newVolumeError("resize", name, ErrVolumeNotFound)
+	}
+	return newDriverError(
+		"resize",
+		name,
+		volumeDriverResizer.Resize(volume.Name, pkgmap.StringStringMap(volume.Opts).Copy(), volume.Mountpoint, sizeBytes),
+	)
+}
+
+func (a *apiServer) Watch(request *google_protobuf.Empty, server API_WatchServer) (err error) {
+	defer func(start time.Time) { a.Log(request, nil, err, time.Since(start)) }(time.Now())
+	events, unsubscribe := a.eventBroadcaster.subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return grpc.Errorf(codes.ResourceExhausted, "dockervolume: watcher did not keep up with the events")
+			}
+			if err := server.Send(event); err != nil {
+				return err
+			}
+		case <-server.Context().Done():
+			return server.Context().Err()
+		}
+	}
+}
+
+// publish sends an event for the given volume to the watchers.
+func (a *apiServer) publish(eventType EventType, volume *Volume, id string, err error) {
+	event := &Event{
+		Type:      eventType,
+		Volume:    copyVolume(volume),
+		Timestamp: timeToTimestamp(time.Now()),
+		Id:        id,
+	}
+	if err != nil {
+		event.Err = err.Error()
+	}
+	a.eventBroadcaster.publish(event)
+}
+
+// forgetVolume stops managing the volume with the given name without calling
+// the volume driver. It returns false if the volume is not managed anymore.
+func (a *apiServer) forgetVolume(name string) (bool, error) {
+	a.volumeLocker.Lock(name)
+	defer a.volumeLocker.Unlock(name)
+	if _, ok := a.getVolume(name); !ok {
+		return false, nil
+	}
+	return true, a.deleteVolume(name)
+}
+
+// clearMountpoint marks the volume with the given name as not mounted, if it
+// is still mounted on the given mountpoint.
+func (a *apiServer) clearMountpoint(name string, mountpoint string) (bool, error) {
+	a.volumeLocker.Lock(name)
+	defer a.volumeLocker.Unlock(name)
+	volume, ok := a.getVolume(name)
+	if !ok || volume.Mountpoint != mountpoint {
+		return false, nil
+	}
+	volume.Mountpoint = ""
+	volume.MountIds = nil
+	return true, a.putVolume(volume)
+}
+
+// adoptVolume manages the volume with the given name again, without opts and
+// without calling the volume driver. It returns false if the volume is already managed.
+func (a *apiServer) adoptVolume(name string) (bool, error) {
+	a.volumeLocker.Lock(name)
+	defer a.volumeLocker.Unlock(name)
+	if _, ok := a.getVolume(name); ok {
+		return false, nil
+	}
+	return true, a.putVolume(&Volume{Name: name})
+}
+
+// describeVolume sets the status and the disk usage of the given copy of a
+// volume. Neither can be canceled, so if ctx is done first, they are left
+// running in the background and the status only has the error of ctx.
+func (a *apiServer) describeVolume(ctx context.Context, volume *Volume) *Volume {
+	volumeC := make(chan *Volume, 1)
+	go func(volume *Volume) {
+		volumeC <- a.describeVolumeNow(volume)
+	}(copyVolume(volume))
+	select {
+	case described := <-volumeC:
+		return described
+	case <-ctx.Done():
+		volume.Status = map[string]string{
+			statusErrorKey: ctx.Err().Error(),
+		}
+		return volume
+	}
+}
+
+func (a *apiServer) describeVolumeNow(volume *Volume) *Volume {
+	if volumeDriverStatusReporter, ok := a.volumeDriver.(VolumeDriverStatusReporter); ok {
+		status, err := volumeDriverStatusReporter.Status(volume.Name, pkgmap.StringStringMap(volume.Opts).Copy(), volume.Mountpoint)
+		if err != nil {
+			status = map[string]string{
+				statusErrorKey: err.Error(),
+			}
+		}
+		volume.Status = status
+	}
+	if volume.Mountpoint != "" && filepath.IsAbs(volume.Mountpoint) {
+		// the mountpoint may be gone, in which case there is no usage to report
+		if usage, err := diskUsage(volume.Mountpoint); err == nil {
+			volume.Usage = usage
+		}
+	}
+	return volume
+}
+
+// getVolume returns a copy of the volume with the given name. The copy can be
+// modified and passed to putVolume while holding the volumeLocker lock for the name.
+func (a *apiServer) getVolume(name string) (*Volume, bool) {
 	a.lock.RLock()
 	defer a.lock.RUnlock()
-	volumes := make([]*Volume, len(a.nameToVolume))
-	i := 0
-	for _, volume := range a.nameToVolume {
-		volumes[i] = copyVolume(volume)
-		i++
+	volume, ok := a.nameToVolume[name]
+	if !ok {
+		return nil, false
 	}
-	return &Volumes{
-		Volume: volumes,
-	}, nil
+	return copyVolume(volume), true
+}
+
+// putVolume persists the volume, and only then makes it visible, so that the
+// volumes in memory never differ from the VolumeStore if it fails.
+func (a *apiServer) putVolume(volume *Volume) error {
+	if err := a.volumeStore.Put(volume); err != nil {
+		return err
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.nameToVolume[volume.Name] = volume
+	return nil
+}
+
+func (a *apiServer) deleteVolume(name string) error {
+	if err := a.volumeStore.Delete(name); err != nil {
+		return err
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	delete(a.nameToVolume, name)
+	return nil
 }
 
 func fromNameOptsRequest(request *NameOptsRequest) (string, map[string]string) {
@@ -199,6 +810,10 @@ func fromNameRequest(request *NameRequest) string {
 	return request.Name
 }
 
+func fromNameIDRequest(request *NameIDRequest) (string, string) {
+	return request.Name, request.Id
+}
+
 func toErrResponse(err error) (*ErrResponse, error) {
 	response := &ErrResponse{}
 	if err != nil {
@@ -217,20 +832,46 @@ func toMountpointErrResponse(mountpoint string, err error) (*MountpointErrRespon
 	return response, nil
 }
 
-func doNameOptsToErr(request *NameOptsRequest, f func(string, map[string]string) error) (*ErrResponse, error) {
+func toVolumeErrResponse(volume *Volume, err error) (*VolumeErrResponse, error) {
+	response := &VolumeErrResponse{
+		Volume: toPluginVolume(volume),
+	}
+	if err != nil {
+		response.Err = err.Error()
+	}
+	return response, nil
+}
+
+func doNameOptsToErr(ctx context.Context, request *NameOptsRequest, f func(context.Context, string, map[string]string) error) (*ErrResponse, error) {
 	name, opts := fromNameOptsRequest(request)
-	return toErrResponse(f(name, opts))
+	return toErrResponse(f(ctx, name, opts))
 }
 
-func doNameToErr(request *NameRequest, f func(string) error) (*ErrResponse, error) {
-	return toErrResponse(f(fromNameRequest(request)))
+func doNameToErr(ctx context.Context, request *NameRequest, f func(context.Context, string) error) (*ErrResponse, error) {
+	return toErrResponse(f(ctx, fromNameRequest(request)))
 }
 
-func doNameToMountpointErr(request *NameRequest, f func(string) (string, error)) (*MountpointErrResponse, error) {
-	mountpoint, err := f(fromNameRequest(request))
+func doNameToMountpointErr(ctx context.Context, request *NameRequest, f func(context.Context, string) (string, error)) (*MountpointErrResponse, error) {
+	mountpoint, err := f(ctx, fromNameRequest(request))
 	return toMountpointErrResponse(mountpoint, err)
 }
 
+func doNameIDToErr(ctx context.Context, request *NameIDRequest, f func(context.Context, string, string) error) (*ErrResponse, error) {
+	name, id := fromNameIDRequest(request)
+	return toErrResponse(f(ctx, name, id))
+}
+
+func doNameIDToMountpointErr(ctx context.Context, request *NameIDRequest, f func(context.Context, string, string) (string, error)) (*MountpointErrResponse, error) {
+	name, id := fromNameIDRequest(request)
+	mountpoint, err := f(ctx, name, id)
+	return toMountpointErrResponse(mountpoint, err)
+}
+
+func doNameToVolumeErr(ctx context.Context, request *NameRequest, f func(context.Context, string) (*Volume, error)) (*VolumeErrResponse, error) {
+	volume, err := f(ctx, fromNameRequest(request))
+	return toVolumeErrResponse(volume, err)
+}
+
 func copyVolume(volume *Volume) *Volume {
 	if volume == nil {
 		return nil
@@ -239,5 +880,165 @@ func copyVolume(volume *Volume) *Volume {
 		Name:       volume.Name,
 		Opts:       pkgmap.StringStringMap(volume.Opts).Copy(),
 		Mountpoint: volume.Mountpoint,
+		MountIds:   copyStrings(volume.MountIds),
+		CreatedAt:  copyTimestamp(volume.CreatedAt),
+		Status:     copyStatus(volume.Status),
+		Usage:      copyDiskUsage(volume.Usage),
+	}
+}
+
+func copyTimestamp(timestamp *google_protobuf.Timestamp) *google_protobuf.Timestamp {
+	if timestamp == nil {
+		return nil
+	}
+	return &google_protobuf.Timestamp{
+		Seconds: timestamp.Seconds,
+		Nanos:   timestamp.Nanos,
+	}
+}
+
+func copyStatus(status map[string]string) map[string]string {
+	if status == nil {
+		return nil
+	}
+	return pkgmap.StringStringMap(status).Copy()
+}
+
+func copyDiskUsage(diskUsage *DiskUsage) *DiskUsage {
+	if diskUsage == nil {
+		return nil
+	}
+	return &DiskUsage{
+		TotalBytes:     diskUsage.TotalBytes,
+		UsedBytes:      diskUsage.UsedBytes,
+		AvailableBytes: diskUsage.AvailableBytes,
+	}
+}
+
+// toPluginVolume returns the volume as the docker volume plugin API returns it.
+func toPluginVolume(volume *Volume) *PluginVolume {
+	if volume == nil {
+		return nil
+	}
+	pluginVolume := &PluginVolume{
+		Name:       volume.Name,
+		Mountpoint: volume.Mountpoint,
+		Status:     copyStatus(volume.Status),
+	}
+	if volume.CreatedAt != nil {
+		pluginVolume.CreatedAt = timestampToTime(volume.CreatedAt).Format(time.RFC3339)
+	}
+	return pluginVolume
+}
+
+func toPluginVolumes(volumes []*Volume) []*PluginVolume {
+	pluginVolumes := make([]*PluginVolume, len(volumes))
+	for i, volume := range volumes {
+		pluginVolumes[i] = toPluginVolume(volume)
+	}
+	return pluginVolumes
+}
+
+// fromPluginVolume returns the fields of the Volume that the docker volume
+// plugin API has.
+func fromPluginVolume(pluginVolume *PluginVolume) (*Volume, error) {
+	if pluginVolume == nil {
+		return nil, nil
+	}
+	volume := &Volume{
+		Name:       pluginVolume.Name,
+		Mountpoint: pluginVolume.Mountpoint,
+		Status:     copyStatus(pluginVolume.Status),
+	}
+	if pluginVolume.CreatedAt != "" {
+		createdAt, err := time.Parse(time.RFC3339, pluginVolume.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		volume.CreatedAt = timeToTimestamp(createdAt)
+	}
+	return volume, nil
+}
+
+func fromPluginVolumes(pluginVolumes []*PluginVolume) ([]*Volume, error) {
+	volumes := make([]*Volume, len(pluginVolumes))
+	for i, pluginVolume := range pluginVolumes {
+		volume, err := fromPluginVolume(pluginVolume)
+		if err != nil {
+			return nil, err
+		}
+		volumes[i] = volume
+	}
+	return volumes, nil
+}
+
+func timeToTimestamp(t time.Time) *google_protobuf.Timestamp {
+	return &google_protobuf.Timestamp{
+		Seconds: t.Unix(),
+		Nanos:   int32(t.Nanosecond()),
+	}
+}
+
+func timestampToTime(timestamp *google_protobuf.Timestamp) time.Time {
+	return time.Unix(timestamp.Seconds, int64(timestamp.Nanos)).UTC()
+}
+
+func copyCapabilities(capabilities *Capabilities) *Capabilities {
+	if capabilities == nil {
+		return nil
+	}
+	return &Capabilities{
+		Scope: capabilities.Scope,
+	}
+}
+
+type volumesByName []*Volume
+
+func (v volumesByName) Len() int           { return len(v) }
+func (v volumesByName) Less(i, j int) bool { return v[i].Name < v[j].Name }
+func (v volumesByName) Swap(i, j int)      { v[i], v[j] = v[j], v[i] }
+
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	c := make([]string, len(s))
+	copy(c, s)
+	return c
+}
+
+func toStringSet(s []string) map[string]bool {
+	set := make(map[string]bool, len(s))
+	for _, element := range s {
+		set[element] = true
+	}
+	return set
+}
+
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return !os.IsNotExist(err)
+}
+
+func containsString(s []string, e string) bool {
+	for _, element := range s {
+		if element == e {
+			return true
+		}
+	}
+	return false
+}
+
+// removeString returns s without the first e, or nil if nothing is left.
+func removeString(s []string, e string) []string {
+	var r []string
+	removed := false
+	for _, element := range s {
+		if element == e && !removed {
+			removed = true
+			continue
+		}
+		r = append(r, element)
 	}
+	return r
 }
@@ -0,0 +1,40 @@
+/*
+Package main contains a docker volume plugin binary for ephemeral in-memory volumes backed by tmpfs.
+
+See go.pedge.io/dockervolume/driver/tmpfs for the opts of the volumes.
+*/
+package main
+
+import (
+	"go.pedge.io/dockerplugin"
+	"go.pedge.io/dockervolume"
+	"go.pedge.io/dockervolume/driver/tmpfs"
+	"go.pedge.io/env"
+)
+
+type appEnv struct {
+	// VolumeDriverName is the name of the plugin, used with docker run --volume-driver.
+	VolumeDriverName string `env:"VOLUME_DRIVER_NAME,default=dockervolume-tmpfs"`
+	// BaseDirPath is the directory the volumes are mounted in.
+	BaseDirPath string `env:"BASE_DIR,default=/run/dockervolume-tmpfs"`
+	// Group is the group of the Unix socket of the plugin.
+	Group string `env:"GROUP,default=root"`
+}
+
+func main() {
+	env.Main(do, &appEnv{})
+}
+
+func do(appEnvObj interface{}) error {
+	appEnv := appEnvObj.(*appEnv)
+	volumeDriver, err := tmpfs.NewVolumeDriver(appEnv.BaseDirPath)
+	if err != nil {
+		return err
+	}
+	return dockervolume.NewUnixServer(
+		volumeDriver,
+		appEnv.VolumeDriverName,
+		appEnv.Group,
+		dockerplugin.ServerOptions{},
+	).Serve()
+}
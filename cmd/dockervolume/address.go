@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	unixPrefix = "unix://"
+	tcpPrefix  = "tcp://"
+)
+
+var (
+	// pluginSocketDirPaths are where docker looks for plugin sockets.
+	pluginSocketDirPaths = []string{"/run/docker/plugins"}
+	// pluginSpecDirPaths are where docker looks for plugin spec files.
+	pluginSpecDirPaths = []string{"/etc/docker/plugins"}
+)
+
+// pluginSpec is the content of a .json plugin spec file.
+type pluginSpec struct {
+	Name string
+	Addr string
+}
+
+// dial dials an address, either host:port, tcp://host:port, or unix:///path/to/socket.
+func dial(address string) (*grpc.ClientConn, error) {
+	switch {
+	case strings.HasPrefix(address, unixPrefix):
+		return grpc.Dial(
+			strings.TrimPrefix(address, unixPrefix),
+			grpc.WithInsecure(),
+			grpc.WithDialer(func(path string, timeout time.Duration) (net.Conn, error) {
+				return net.DialTimeout("unix", path, timeout)
+			}),
+		)
+	case strings.HasPrefix(address, tcpPrefix):
+		return grpc.Dial(strings.TrimPrefix(address, tcpPrefix), grpc.WithInsecure())
+	default:
+		return grpc.Dial(address, grpc.WithInsecure())
+	}
+}
+
+// discoverPlugin returns the address of the plugin with the given name, as docker
+// would find it, first from a socket, then from a .spec or .json spec file.
+func discoverPlugin(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("Invalid plugin name: %s.", name)
+	}
+	for _, dirPath := range pluginSocketDirPaths {
+		for _, socketPath := range []string{
+			filepath.Join(dirPath, name+".sock"),
+			filepath.Join(dirPath, name, name+".sock"),
+		} {
+			fileInfo, err := os.Stat(socketPath)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return "", err
+			}
+			if fileInfo.Mode()&os.ModeSocket != 0 {
+				return unixPrefix + socketPath, nil
+			}
+		}
+	}
+	for _, dirPath := range pluginSpecDirPaths {
+		for _, ext := range []string{".spec", ".json"} {
+			specPath := filepath.Join(dirPath, name+ext)
+			data, err := ioutil.ReadFile(specPath)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return "", err
+			}
+			address, err := parsePluginSpec(ext, data)
+			if err != nil {
+				return "", fmt.Errorf("Invalid plugin spec file %s: %s.", specPath, err.Error())
+			}
+			return address, nil
+		}
+	}
+	return "", fmt.Errorf("Plugin %s not found in %s.", name, strings.Join(append(pluginSocketDirPaths, pluginSpecDirPaths...), ", "))
+}
+
+func parsePluginSpec(ext string, data []byte) (string, error) {
+	var address string
+	if ext == ".json" {
+		spec := &pluginSpec{}
+		if err := json.Unmarshal(data, spec); err != nil {
+			return "", err
+		}
+		address = spec.Addr
+	} else {
+		address = strings.TrimSpace(string(data))
+	}
+	if !strings.HasPrefix(address, unixPrefix) && !strings.HasPrefix(address, tcpPrefix) {
+		return "", fmt.Errorf("address must start with %s or %s, got %q", unixPrefix, tcpPrefix, address)
+	}
+	return address, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscoverPlugin(t *testing.T) {
+	socketDirPath, specDirPath, cleanup := setPluginDirPaths(t)
+	defer cleanup()
+
+	listener, err := net.Listen("unix", filepath.Join(socketDirPath, "foo.sock"))
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	require.NoError(t, os.Mkdir(filepath.Join(socketDirPath, "bar"), 0755))
+	listener, err = net.Listen("unix", filepath.Join(socketDirPath, "bar", "bar.sock"))
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	// not a socket, so the spec file is used
+	require.NoError(t, ioutil.WriteFile(filepath.Join(socketDirPath, "baz.sock"), nil, 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(specDirPath, "baz.spec"), []byte("tcp://localhost:8080\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(specDirPath, "baz.json"), []byte(`{"Name":"baz","Addr":"tcp://localhost:9090"}`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(specDirPath, "qux.json"), []byte(`{"Name":"qux","Addr":"unix:///run/qux.sock"}`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(specDirPath, "invalid.spec"), []byte("localhost:8080"), 0644))
+
+	for name, expected := range map[string]string{
+		"foo": unixPrefix + filepath.Join(socketDirPath, "foo.sock"),
+		"bar": unixPrefix + filepath.Join(socketDirPath, "bar", "bar.sock"),
+		"baz": "tcp://localhost:8080",
+		"qux": "unix:///run/qux.sock",
+	} {
+		address, err := discoverPlugin(name)
+		require.NoError(t, err)
+		require.Equal(t, expected, address, name)
+	}
+	for _, name := range []string{"", "../foo", `foo\bar`, "missing", "invalid"} {
+		_, err := discoverPlugin(name)
+		require.Error(t, err, name)
+	}
+}
+
+func TestParsePluginSpec(t *testing.T) {
+	for _, testCase := range []struct {
+		ext      string
+		data     string
+		expected string
+	}{
+		{".spec", "unix:///run/foo.sock", "unix:///run/foo.sock"},
+		{".spec", " tcp://localhost:8080\n", "tcp://localhost:8080"},
+		{".json", `{"Name":"foo","Addr":"tcp://localhost:8080"}`, "tcp://localhost:8080"},
+	} {
+		address, err := parsePluginSpec(testCase.ext, []byte(testCase.data))
+		require.NoError(t, err)
+		require.Equal(t, testCase.expected, address)
+	}
+	for _, testCase := range []struct {
+		ext  string
+		data string
+	}{
+		{".spec", ""},
+		{".spec", "localhost:8080"},
+		{".json", `{"Name":"foo"}`},
+		{".json", "tcp://localhost:8080"},
+	} {
+		_, err := parsePluginSpec(testCase.ext, []byte(testCase.data))
+		require.Error(t, err, testCase.data)
+	}
+}
+
+// setPluginDirPaths points plugin discovery at new temporary directories
+// until cleanup is called.
+func setPluginDirPaths(t *testing.T) (socketDirPath string, specDirPath string, cleanup func()) {
+	dirPath, err := ioutil.TempDir("", "dockervolume")
+	require.NoError(t, err)
+	socketDirPath = filepath.Join(dirPath, "run")
+	specDirPath = filepath.Join(dirPath, "etc")
+	require.NoError(t, os.Mkdir(socketDirPath, 0755))
+	require.NoError(t, os.Mkdir(specDirPath, 0755))
+	origPluginSocketDirPaths, origPluginSpecDirPaths := pluginSocketDirPaths, pluginSpecDirPaths
+	pluginSocketDirPaths, pluginSpecDirPaths = []string{socketDirPath}, []string{specDirPath}
+	return socketDirPath, specDirPath, func() {
+		pluginSocketDirPaths, pluginSpecDirPaths = origPluginSocketDirPaths, origPluginSpecDirPaths
+		_ = os.RemoveAll(dirPath)
+	}
+}
@@ -7,13 +7,13 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/golang/protobuf/jsonpb"
-	"github.com/golang/protobuf/proto"
 	"github.com/spf13/cobra"
 	"go.pedge.io/dockervolume"
 	"go.pedge.io/env"
-	"google.golang.org/grpc"
+	"golang.org/x/net/context"
 )
 
 var (
@@ -33,34 +33,161 @@ func main() {
 func do(appEnvObj interface{}) error {
 	appEnv := appEnvObj.(*appEnv)
 
+	var plugin string
+	var pluginProtocol bool
+	var output string
+	var format string
+	getClient := func() (dockervolume.VolumeDriverClient, error) {
+		// the plugin socket only serves the docker volume plugin protocol
+		if plugin != "" {
+			return nil, fmt.Errorf("--plugin can only be used with --plugin-protocol, set ADDRESS to the address of the dockervolume API instead.")
+		}
+		clientConn, err := dial(appEnv.Address)
+		if err != nil {
+			return nil, err
+		}
+		return dockervolume.NewVolumeDriverClient(dockervolume.NewAPIClient(clientConn)), nil
+	}
+	getPluginAPI := func() (pluginAPI, error) {
+		if !pluginProtocol {
+			return getClient()
+		}
+		address := appEnv.Address
+		if plugin != "" {
+			var err error
+			address, err = discoverPlugin(plugin)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !strings.HasPrefix(address, unixPrefix) && !strings.HasPrefix(address, tcpPrefix) {
+			address = tcpPrefix + address
+		}
+		return dockervolume.NewPluginClient(address, dockervolume.PluginClientOptions{})
+	}
+	getPrinter := func() (*printer, error) {
+		return newPrinter(os.Stdout, output, format)
+	}
+
+	var opts []string
+	create := &cobra.Command{
+		Use:   "create name",
+		Short: "Create a volume.",
+		Long:  "Create a volume with the given name and opts.",
+		Run: cobraFunc(1, func(args []string) error {
+			optsMap, err := parseOpts(opts)
+			if err != nil {
+				return err
+			}
+			client, err := getPluginAPI()
+			if err != nil {
+				return err
+			}
+			return client.Create(args[0], optsMap)
+		}),
+	}
+	create.Flags().StringArrayVarP(&opts, "opt", "o", nil, "An opt for the volume, key=value. Can be repeated.")
+
+	remove := &cobra.Command{
+		Use:   "remove name",
+		Short: "Remove a volume.",
+		Long:  "Remove a volume. The volume must not be mounted.",
+		Run: cobraFunc(1, func(args []string) error {
+			client, err := getPluginAPI()
+			if err != nil {
+				return err
+			}
+			return client.Remove(args[0])
+		}),
+	}
+
+	path := &cobra.Command{
+		Use:   "path name",
+		Short: "Print the mountpoint of a volume.",
+		Long:  "Print the mountpoint of a volume, or an empty line if the volume is not mounted.",
+		Run: cobraFunc(1, func(args []string) error {
+			client, err := getPluginAPI()
+			if err != nil {
+				return err
+			}
+			mountpoint, err := client.Path(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Println(mountpoint)
+			return nil
+		}),
+	}
+
+	mount := &cobra.Command{
+		Use:   "mount name id",
+		Short: "Mount a volume.",
+		Long:  "Mount a volume on behalf of the caller with the given id, and print the mountpoint.",
+		Run: cobraFunc(2, func(args []string) error {
+			client, err := getPluginAPI()
+			if err != nil {
+				return err
+			}
+			mountpoint, err := client.Mount(args[0], args[1])
+			if err != nil {
+				return err
+			}
+			fmt.Println(mountpoint)
+			return nil
+		}),
+	}
+
+	unmount := &cobra.Command{
+		Use:   "unmount name id",
+		Short: "Unmount a volume.",
+		Long:  "Unmount a volume on behalf of the caller with the given id. The volume is only unmounted once every caller unmounted it.",
+		Run: cobraFunc(2, func(args []string) error {
+			client, err := getPluginAPI()
+			if err != nil {
+				return err
+			}
+			return client.Unmount(args[0], args[1])
+		}),
+	}
+
+	cleanupRequest := &dockervolume.CleanupRequest{}
 	cleanup := &cobra.Command{
 		Use:   "cleanup",
 		Short: "Cleanup all existing volumes.",
-		Long:  "Cleanup all existing volumes that the volume driver is currently handling.",
+		Long:  "Cleanup all existing volumes that the volume driver is currently handling, or only the volumes matching the flags.",
 		Run: cobraFunc(0, func(_ []string) error {
-			client, err := getClient(appEnv)
+			printer, err := getPrinter()
 			if err != nil {
 				return err
 			}
-			response, err := client.Cleanup()
+			client, err := getClient()
 			if err != nil {
 				return err
 			}
-			for _, element := range response {
-				if err := marshal(element); err != nil {
-					return err
-				}
+			response, err := client.CleanupVolumes(cleanupRequest)
+			if err != nil {
+				return err
 			}
-			return nil
+			return printer.printVolumes(response)
 		}),
 	}
 
+	cleanup.Flags().BoolVar(&cleanupRequest.DryRun, "dry-run", false, "Only print the volumes that would be removed.")
+	cleanup.Flags().StringVar(&cleanupRequest.NamePrefix, "name-prefix", "", "Only remove volumes whose name starts with this prefix.")
+	cleanup.Flags().StringArrayVarP(&cleanupRequest.Selector, "selector", "l", nil, "Only remove volumes whose opts match every selector, key=value or key.")
+	cleanup.Flags().BoolVar(&cleanupRequest.OnlyUnused, "only-unused", false, "Skip volumes referenced by a container, running or not.")
+	cleanup.Flags().BoolVar(&cleanupRequest.Force, "force", false, "Unmount volumes for every caller before removing them.")
+
 	getVolume := &cobra.Command{
 		Use:   "get-volume name",
 		Short: "Get a volume by name.",
 		Long:  "Get a volume by name.",
 		Run: cobraFunc(1, func(args []string) error {
-			client, err := getClient(appEnv)
+			printer, err := getPrinter()
+			if err != nil {
+				return err
+			}
+			client, err := getClient()
 			if err != nil {
 				return err
 			}
@@ -68,40 +195,108 @@ func do(appEnvObj interface{}) error {
 			if err != nil {
 				return err
 			}
-			return marshal(response)
+			return printer.printVolumes([]*dockervolume.Volume{response})
 		}),
 	}
 
+	listVolumesRequest := &dockervolume.ListVolumesRequest{}
+	var mountState string
 	listVolumes := &cobra.Command{
 		Use:   "list-volumes",
 		Short: "List all volumes controlled by this driver",
-		Long:  "List all volumes controlled by this driver",
+		Long:  "List all volumes controlled by this driver, sorted by name.\n\nIf --page-size is set and there are more volumes, the token for the next page is printed to stderr.",
 		Run: cobraFunc(0, func(_ []string) error {
-			client, err := getClient(appEnv)
+			mountStateValue, ok := dockervolume.MountState_value["MOUNT_STATE_"+strings.ToUpper(mountState)]
+			if !ok {
+				return fmt.Errorf("Invalid mount state: %s.", mountState)
+			}
+			listVolumesRequest.MountState = dockervolume.MountState(mountStateValue)
+			printer, err := getPrinter()
+			if err != nil {
+				return err
+			}
+			client, err := getClient()
 			if err != nil {
 				return err
 			}
-			response, err := client.ListVolumes()
+			response, err := client.ListVolumesPage(listVolumesRequest)
 			if err != nil {
 				return err
 			}
-			for _, element := range response {
-				if err := marshal(element); err != nil {
-					return err
-				}
+			if err := printer.printVolumes(response.Volume); err != nil {
+				return err
+			}
+			if response.NextPageToken != "" {
+				fmt.Fprintf(os.Stderr, "next page token: %s\n", response.NextPageToken)
 			}
 			return nil
 		}),
 	}
+	listVolumes.Flags().StringVar(&listVolumesRequest.NamePrefix, "name-prefix", "", "Only list volumes whose name starts with this prefix.")
+	listVolumes.Flags().StringArrayVarP(&listVolumesRequest.Selector, "selector", "l", nil, "Only list volumes whose opts match every selector, key=value or key.")
+	listVolumes.Flags().StringVar(&mountState, "mount-state", "any", "Only list volumes in this state, one of any, mounted or unmounted.")
+	listVolumes.Flags().Uint32Var(&listVolumesRequest.PageSize, "page-size", 0, "The maximum number of volumes to list, all if 0.")
+	listVolumes.Flags().StringVar(&listVolumesRequest.PageToken, "page-token", "", "The token of the page to list, printed by the previous call.")
+
+	var repair bool
+	reconcile := &cobra.Command{
+		Use:   "reconcile",
+		Short: "Reconcile volumes with docker and the volume driver.",
+		Long:  "Reconcile the volumes controlled by this driver with the volumes docker knows about and the volumes of the volume driver, and report the differences.",
+		Run: cobraFunc(0, func(_ []string) error {
+			printer, err := getPrinter()
+			if err != nil {
+				return err
+			}
+			client, err := getClient()
+			if err != nil {
+				return err
+			}
+			response, err := client.Reconcile(repair)
+			if err != nil {
+				return err
+			}
+			return printer.printMessage(response)
+		}),
+	}
+	reconcile.Flags().BoolVar(&repair, "repair", false, "Repair the differences that can be repaired.")
+
+	watch := &cobra.Command{
+		Use:   "watch",
+		Short: "Watch the events for all volumes.",
+		Long:  "Watch the events for all volumes controlled by this driver.\n\nWith --output json, one JSON object is printed per line. With --output name, the name of the volume of each event is printed.",
+		Run: cobraFunc(0, func(_ []string) error {
+			printer, err := getPrinter()
+			if err != nil {
+				return err
+			}
+			client, err := getClient()
+			if err != nil {
+				return err
+			}
+			return client.Watch(context.Background(), printer.printEvent)
+		}),
+	}
 
 	rootCmd := &cobra.Command{
 		Use:   "dockervolume",
 		Short: "Access a Docker volume driver.",
-		Long:  "Access a Dockervolume driver.\n\nThe environment variable ADDRESS controls what server the CLI connects to, the default is 0.0.0.0:2150.",
+		Long:  "Access a Dockervolume driver.\n\nThe environment variable ADDRESS controls what server the CLI connects to, the default is 0.0.0.0:2150.\nADDRESS can be host:port, tcp://host:port, or unix:///path/to/socket. Use --plugin to find the address of a plugin by name instead.",
 	}
+	rootCmd.PersistentFlags().BoolVar(&pluginProtocol, "plugin-protocol", false, "Call create, remove, path, mount and unmount with the docker volume plugin protocol, as docker does, instead of the dockervolume API. Works with any volume plugin.")
+	rootCmd.PersistentFlags().StringVar(&plugin, "plugin", "", "The name of a plugin to connect to, found as docker would in /run/docker/plugins and /etc/docker/plugins. Overrides ADDRESS, only with --plugin-protocol.")
+	rootCmd.PersistentFlags().StringVar(&output, "output", outputJSON, "The output format, one of json, yaml, table or name. table is only supported for volumes, name for volumes and events.")
+	rootCmd.PersistentFlags().StringVar(&format, "format", "", "A Go template executed for each volume, event or response, for example '{{.Name}}'. Overrides --output.")
+	rootCmd.AddCommand(create)
+	rootCmd.AddCommand(remove)
+	rootCmd.AddCommand(path)
+	rootCmd.AddCommand(mount)
+	rootCmd.AddCommand(unmount)
 	rootCmd.AddCommand(cleanup)
 	rootCmd.AddCommand(getVolume)
 	rootCmd.AddCommand(listVolumes)
+	rootCmd.AddCommand(reconcile)
+	rootCmd.AddCommand(watch)
 	return rootCmd.Execute()
 }
 
@@ -126,18 +321,24 @@ func check(err error) {
 	}
 }
 
-func getClient(appEnv *appEnv) (dockervolume.VolumeDriverClient, error) {
-	clientConn, err := grpc.Dial(appEnv.Address, grpc.WithInsecure())
-	if err != nil {
-		return nil, err
+func parseOpts(opts []string) (map[string]string, error) {
+	optsMap := make(map[string]string)
+	for _, opt := range opts {
+		split := strings.SplitN(opt, "=", 2)
+		if len(split) != 2 || split[0] == "" {
+			return nil, fmt.Errorf("Invalid opt, must be key=value: %s.", opt)
+		}
+		optsMap[split[0]] = split[1]
 	}
-	return dockervolume.NewVolumeDriverClient(dockervolume.NewAPIClient(clientConn)), nil
+	return optsMap, nil
 }
 
-func marshal(message proto.Message) error {
-	if err := marshaler.Marshal(os.Stdout, message); err != nil {
-		return err
-	}
-	fmt.Println()
-	return nil
+// pluginAPI is the docker volume plugin API shared by the dockervolume API
+// and the docker volume plugin protocol.
+type pluginAPI interface {
+	Create(name string, opts map[string]string) error
+	Remove(name string) error
+	Path(name string) (string, error)
+	Mount(name string, id string) (string, error)
+	Unmount(name string, id string) error
 }
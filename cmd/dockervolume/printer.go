@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/ghodss/yaml"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"go.pedge.io/dockervolume"
+)
+
+const (
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+	outputTable = "table"
+	outputName  = "name"
+
+	maxOptsSummaryLength = 40
+)
+
+var (
+	outputs = []string{outputJSON, outputYAML, outputTable, outputName}
+
+	lineMarshaler = &jsonpb.Marshaler{}
+)
+
+// printer prints messages in the format chosen with --output or --format.
+type printer struct {
+	writer   io.Writer
+	output   string
+	template *template.Template
+	// printedYAML is set once a YAML document was printed, to separate the next one.
+	printedYAML bool
+}
+
+func newPrinter(writer io.Writer, output string, format string) (*printer, error) {
+	var tmpl *template.Template
+	if format != "" {
+		var err error
+		tmpl, err = template.New("format").Parse(format)
+		if err != nil {
+			return nil, err
+		}
+	} else if !containsString(outputs, output) {
+		return nil, fmt.Errorf("Invalid output %s, must be one of %s.", output, strings.Join(outputs, ", "))
+	}
+	return &printer{
+		writer,
+		output,
+		tmpl,
+		false,
+	}, nil
+}
+
+func (p *printer) printVolumes(volumes []*dockervolume.Volume) error {
+	switch {
+	case p.template != nil:
+		for _, volume := range volumes {
+			if err := p.printTemplate(volume); err != nil {
+				return err
+			}
+		}
+		return nil
+	case p.output == outputTable:
+		tabWriter := tabwriter.NewWriter(p.writer, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tabWriter, "NAME\tMOUNTED\tMOUNTPOINT\tOPTS")
+		for _, volume := range volumes {
+			mounted := "no"
+			if len(volume.MountIds) > 0 {
+				mounted = "yes"
+			}
+			fmt.Fprintf(tabWriter, "%s\t%s\t%s\t%s\n", volume.Name, mounted, volume.Mountpoint, optsSummary(volume.Opts))
+		}
+		return tabWriter.Flush()
+	case p.output == outputName:
+		for _, volume := range volumes {
+			fmt.Fprintln(p.writer, volume.Name)
+		}
+		return nil
+	default:
+		for _, volume := range volumes {
+			if err := p.printMessage(volume); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// printEvent prints an event of a stream of events, so JSON is printed on one line.
+func (p *printer) printEvent(event *dockervolume.Event) error {
+	switch {
+	case p.template != nil:
+		return p.printTemplate(event)
+	case p.output == outputName:
+		if event.Volume == nil {
+			return nil
+		}
+		_, err := fmt.Fprintln(p.writer, event.Volume.Name)
+		return err
+	case p.output == outputJSON:
+		if err := lineMarshaler.Marshal(p.writer, event); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(p.writer)
+		return err
+	default:
+		return p.printMessage(event)
+	}
+}
+
+// printMessage prints a message as JSON, YAML or with the template.
+func (p *printer) printMessage(message proto.Message) error {
+	switch {
+	case p.template != nil:
+		return p.printTemplate(message)
+	case p.output == outputJSON:
+		if err := marshaler.Marshal(p.writer, message); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(p.writer)
+		return err
+	case p.output == outputYAML:
+		buffer := &bytes.Buffer{}
+		if err := lineMarshaler.Marshal(buffer, message); err != nil {
+			return err
+		}
+		data, err := yaml.JSONToYAML(buffer.Bytes())
+		if err != nil {
+			return err
+		}
+		if p.printedYAML {
+			if _, err := fmt.Fprintln(p.writer, "---"); err != nil {
+				return err
+			}
+		}
+		p.printedYAML = true
+		_, err = p.writer.Write(data)
+		return err
+	default:
+		return fmt.Errorf("Output %s is not supported by this command.", p.output)
+	}
+}
+
+func (p *printer) printTemplate(data interface{}) error {
+	if err := p.template.Execute(p.writer, data); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(p.writer)
+	return err
+}
+
+// optsSummary returns the opts as sorted key=value pairs, truncated to maxOptsSummaryLength
+// characters if too long for a table.
+func optsSummary(opts map[string]string) string {
+	pairs := make([]string, 0, len(opts))
+	for key, value := range opts {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	summary := strings.Join(pairs, ",")
+	// truncated by rune, so that a multi-byte character is never cut
+	if runes := []rune(summary); len(runes) > maxOptsSummaryLength {
+		summary = string(runes[:maxOptsSummaryLength-3]) + "..."
+	}
+	return summary
+}
+
+func containsString(s []string, e string) bool {
+	for _, element := range s {
+		if element == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/stretchr/testify/require"
+	"go.pedge.io/dockervolume"
+)
+
+var (
+	testVolumes = []*dockervolume.Volume{
+		{
+			Name:       "bar",
+			Opts:       map[string]string{"size": "1G", "fs": "ext4"},
+			Mountpoint: "/mnt/bar",
+			MountIds:   []string{"container"},
+		},
+		{
+			Name: "foo",
+		},
+	}
+)
+
+func TestPrintTable(t *testing.T) {
+	output := printTestVolumes(t, outputTable, "")
+	require.Equal(
+		t,
+		strings.Join(
+			[]string{
+				"NAME  MOUNTED  MOUNTPOINT  OPTS",
+				"bar   yes      /mnt/bar    fs=ext4,size=1G",
+				"foo   no                   ",
+				"",
+			},
+			"\n",
+		),
+		output,
+	)
+}
+
+func TestPrintJSON(t *testing.T) {
+	output := printTestVolumes(t, outputJSON, "")
+	decoder := jsonpb.Unmarshaler{}
+	var volumes []*dockervolume.Volume
+	for _, document := range strings.SplitAfter(output, "}\n") {
+		if document == "" {
+			continue
+		}
+		volume := &dockervolume.Volume{}
+		require.NoError(t, decoder.Unmarshal(strings.NewReader(document), volume))
+		volumes = append(volumes, volume)
+	}
+	require.Equal(t, testVolumes, volumes)
+}
+
+func TestPrintFormat(t *testing.T) {
+	output := printTestVolumes(t, outputTable, `{{.Name}} {{.Mountpoint}} {{index .Opts "size"}}`)
+	require.Equal(t, "bar /mnt/bar 1G\nfoo  \n", output)
+	_, err := newPrinter(&bytes.Buffer{}, outputTable, "{{.Name")
+	require.Error(t, err)
+	_, err = newPrinter(&bytes.Buffer{}, "xml", "")
+	require.Error(t, err)
+}
+
+func TestOptsSummary(t *testing.T) {
+	require.Equal(t, "", optsSummary(nil))
+	require.Equal(t, "a=1,b=2", optsSummary(map[string]string{"b": "2", "a": "1"}))
+	summary := optsSummary(map[string]string{"path": strings.Repeat("é", 50)})
+	require.Equal(t, "path="+strings.Repeat("é", maxOptsSummaryLength-8)+"...", summary)
+	require.Len(t, []rune(summary), maxOptsSummaryLength)
+}
+
+func printTestVolumes(t *testing.T, output string, format string) string {
+	buffer := &bytes.Buffer{}
+	printer, err := newPrinter(buffer, output, format)
+	require.NoError(t, err)
+	require.NoError(t, printer.printVolumes(testVolumes))
+	return buffer.String()
+}
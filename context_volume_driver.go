@@ -0,0 +1,58 @@
+package dockervolume
+
+import (
+	"time"
+
+	"go.pedge.io/pkg/map"
+	"golang.org/x/net/context"
+)
+
+// contextVolumeDriver adapts a VolumeDriver that does not implement
+// ContextVolumeDriver. The calls cannot be interrupted, but a call whose
+// context is already done is never made.
+type contextVolumeDriver struct {
+	volumeDriver VolumeDriver
+}
+
+func newContextVolumeDriver(volumeDriver VolumeDriver) ContextVolumeDriver {
+	if contextVolumeDriver, ok := volumeDriver.(ContextVolumeDriver); ok {
+		return contextVolumeDriver
+	}
+	return &contextVolumeDriver{volumeDriver}
+}
+
+func (c *contextVolumeDriver) CreateContext(ctx context.Context, name string, opts pkgmap.StringStringMap) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.volumeDriver.Create(name, opts)
+}
+
+func (c *contextVolumeDriver) RemoveContext(ctx context.Context, name string, opts pkgmap.StringStringMap, mountpoint string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.volumeDriver.Remove(name, opts, mountpoint)
+}
+
+func (c *contextVolumeDriver) MountContext(ctx context.Context, name string, opts pkgmap.StringStringMap) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return c.volumeDriver.Mount(name, opts)
+}
+
+func (c *contextVolumeDriver) UnmountContext(ctx context.Context, name string, opts pkgmap.StringStringMap, mountpoint string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.volumeDriver.Unmount(name, opts, mountpoint)
+}
+
+// withTimeout is context.WithTimeout, except that a zero timeout means no timeout.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
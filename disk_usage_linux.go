@@ -0,0 +1,19 @@
+//go:build linux
+// +build linux
+
+package dockervolume
+
+import "syscall"
+
+func diskUsage(path string) (*DiskUsage, error) {
+	var statfs syscall.Statfs_t
+	if err := syscall.Statfs(path, &statfs); err != nil {
+		return nil, err
+	}
+	blockSize := uint64(statfs.Bsize)
+	return &DiskUsage{
+		TotalBytes:     statfs.Blocks * blockSize,
+		UsedBytes:      (statfs.Blocks - statfs.Bfree) * blockSize,
+		AvailableBytes: statfs.Bavail * blockSize,
+	}, nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package dockervolume
+
+import "errors"
+
+func diskUsage(path string) (*DiskUsage, error) {
+	return nil, errors.New("dockervolume: disk usage is only supported on linux")
+}
@@ -30,6 +30,33 @@ To launch your plugin using TCP, do:
 	  ).Serve()
 	}
 
+To set APIServerOptions, use NewUnixServerWithOptions or NewTCPServerWithOptions
+instead, which return an error if the options are invalid.
+
+By default, the state of the volumes is only kept in memory. To keep the state
+across restarts of your plugin, set a VolumeStore in the ServerOptions:
+
+	func launch(volumeDriver dockervolume.VolumeDriver) error {
+	  volumeStore, err := dockervolume.NewFileVolumeStore("/var/lib/volume_driver_name")
+	  if err != nil {
+		return err
+	  }
+	  server, err := dockervolume.NewUnixServerWithOptions(
+		volumeDriver,
+		"volume_driver_name",
+		"root",
+		dockervolume.ServerOptions{
+		  APIServerOptions: dockervolume.APIServerOptions{
+			VolumeStore: volumeStore,
+		  },
+		},
+	  )
+	  if err != nil {
+		return err
+	  }
+	  return server.Serve()
+	}
+
 Examples:
 
 https://github.com/peter-edge/go-dockervolume/tree/master/example/cmd/dockervolume-example
@@ -38,11 +65,80 @@ https://github.com/pachyderm/pachyderm/tree/master/src/cmd/pfs-volume-driver
 package dockervolume // import "go.pedge.io/dockervolume"
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
 	"go.pedge.io/dockerplugin"
 	"go.pedge.io/pkg/map"
+	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 )
 
+const (
+	// ScopeLocal is the scope of a volume driver whose volumes are only
+	// visible on the host they were created on. This is the default.
+	ScopeLocal = "local"
+	// ScopeGlobal is the scope of a volume driver whose volumes are visible
+	// on every host in the cluster. Docker will only create such a volume once.
+	ScopeGlobal = "global"
+)
+
+var (
+	// ErrVolumeNotFound is the error for a volume that does not exist.
+	ErrVolumeNotFound = errors.New("volume not found")
+	// ErrVolumeExists is the error when creating a volume that already exists.
+	ErrVolumeExists = errors.New("volume already exists")
+	// ErrAlreadyMounted is the error when removing a volume that is still mounted.
+	ErrAlreadyMounted = errors.New("volume already mounted")
+	// ErrNotMounted is the error when unmounting a volume that is not mounted
+	// by the caller.
+	ErrNotMounted = errors.New("volume not mounted")
+	// ErrUnimplemented is the error when the VolumeDriver does not implement
+	// the optional interface needed for an operation.
+	ErrUnimplemented = errors.New("not implemented by the volume driver")
+)
+
+// VolumeError is the error for a failed operation on a volume.
+//
+// Errors returned by VolumeDriverClient are VolumeErrors when the server
+// returned a VolumeError, so errors.Is(err, ErrVolumeNotFound) and
+// errors.As(err, &driverError) work on both sides.
+type VolumeError struct {
+	// Op is the operation, for example "create" or "mount".
+	Op string
+	// Name is the name of the volume.
+	Name string
+	// Err is the cause, either one of the Err variables or a *DriverError.
+	Err error
+}
+
+func (e *VolumeError) Error() string {
+	return volumeErrorPrefix + e.Op + " " + e.Name + ": " + e.Err.Error()
+}
+
+// Unwrap returns the cause.
+func (e *VolumeError) Unwrap() error {
+	return e.Err
+}
+
+// DriverError is the error for a failed VolumeDriver call.
+type DriverError struct {
+	// Err is the error returned by the VolumeDriver. On the client side,
+	// only the message of the original error is kept.
+	Err error
+}
+
+func (e *DriverError) Error() string {
+	return driverErrorPrefix + e.Err.Error()
+}
+
+// Unwrap returns the error returned by the VolumeDriver.
+func (e *DriverError) Unwrap() error {
+	return e.Err
+}
+
 // VolumeDriver is the interface that should be implemented for custom volume drivers.
 type VolumeDriver interface {
 	// Create a volume with the given name and opts.
@@ -51,13 +147,110 @@ type VolumeDriver interface {
 	// given when created, and mountpoint when mounted, if ever mounted.
 	Remove(name string, opts pkgmap.StringStringMap, mountpoint string) (err error)
 	// Mount the given volume and return the mountpoint. opts were the opts
-	// given when created.
+	// given when created. A volume shared by multiple callers is only
+	// mounted by the first of them.
 	Mount(name string, opts pkgmap.StringStringMap) (mountpoint string, err error)
 	// Unmount the given volume. opts were the opts and mountpoint were the
-	// opts given when created, and mountpoint when mounted.
+	// opts given when created, and mountpoint when mounted. A volume shared
+	// by multiple callers is only unmounted when the last of them unmounts it.
 	Unmount(name string, opts pkgmap.StringStringMap, mountpoint string) (err error)
 }
 
+// ContextVolumeDriver is an optional interface that a VolumeDriver can
+// implement to receive the context of each request.
+//
+// If a VolumeDriver implements ContextVolumeDriver, these methods are called
+// instead of the corresponding VolumeDriver methods. The context is done when
+// the request is cancelled or when the timeout for the operation configured in
+// APIServerOptions expires, and implementations should give up as soon as
+// possible when that happens.
+//
+// VolumeDriver methods are not interrupted, but are not called at all if
+// the context is already done.
+type ContextVolumeDriver interface {
+	// CreateContext is Create with a context.
+	CreateContext(ctx context.Context, name string, opts pkgmap.StringStringMap) (err error)
+	// RemoveContext is Remove with a context.
+	RemoveContext(ctx context.Context, name string, opts pkgmap.StringStringMap, mountpoint string) (err error)
+	// MountContext is Mount with a context.
+	MountContext(ctx context.Context, name string, opts pkgmap.StringStringMap) (mountpoint string, err error)
+	// UnmountContext is Unmount with a context.
+	UnmountContext(ctx context.Context, name string, opts pkgmap.StringStringMap, mountpoint string) (err error)
+}
+
+// VolumeDriverLister is an optional interface that a VolumeDriver can
+// implement to list the volumes that exist in its backend.
+//
+// If a VolumeDriver implements VolumeDriverLister, Reconcile also compares
+// the volumes managed by the API with the volumes of the VolumeDriver.
+type VolumeDriverLister interface {
+	// ListVolumeNames returns the names of all volumes in the backend.
+	ListVolumeNames() ([]string, error)
+}
+
+// VolumeDriverStatusReporter is an optional interface that a VolumeDriver can
+// implement to report the status of its volumes.
+//
+// The status is also set on the volumes returned by Get and GetVolume, and is
+// reported to docker. If Status fails, the status of the volume only has the
+// key "error", with the error as value.
+type VolumeDriverStatusReporter interface {
+	// Status returns the status of the given volume. opts were the opts given
+	// when created, and mountpoint is the mountpoint if mounted.
+	Status(name string, opts pkgmap.StringStringMap, mountpoint string) (status map[string]string, err error)
+}
+
+// VolumeDriverSnapshotter is an optional interface that a VolumeDriver can
+// implement to snapshot its volumes.
+type VolumeDriverSnapshotter interface {
+	// Snapshot the given volume to a snapshot with the given name. opts were
+	// the opts given when created, and mountpoint is the mountpoint if mounted.
+	Snapshot(name string, opts pkgmap.StringStringMap, mountpoint string, snapshotName string) (err error)
+}
+
+// VolumeDriverResizer is an optional interface that a VolumeDriver can
+// implement to resize its volumes.
+type VolumeDriverResizer interface {
+	// Resize the given volume to the given size in bytes. opts were the opts
+	// given when created, and mountpoint is the mountpoint if mounted.
+	Resize(name string, opts pkgmap.StringStringMap, mountpoint string, sizeBytes uint64) (err error)
+}
+
+// DockerClient is the part of the docker API used by Cleanup and Reconcile.
+//
+// *docker.Client from github.com/fsouza/go-dockerclient implements DockerClient.
+type DockerClient interface {
+	ListVolumes(opts docker.ListVolumesOptions) ([]docker.Volume, error)
+	RemoveVolume(name string) error
+	ListContainers(opts docker.ListContainersOptions) ([]docker.APIContainers, error)
+}
+
+// VolumeStore persists the state of the volumes managed by an APIServer.
+type VolumeStore interface {
+	// Put the given volume, replacing any volume with the same name.
+	Put(volume *Volume) error
+	// Delete the volume with the given name. Deleting a volume that
+	// does not exist is not an error.
+	Delete(name string) error
+	// List all volumes.
+	List() ([]*Volume, error)
+}
+
+// NewMemoryVolumeStore returns a new VolumeStore that keeps volumes in memory.
+//
+// The state is lost when the process exits.
+func NewMemoryVolumeStore() VolumeStore {
+	return newMemoryVolumeStore()
+}
+
+// NewFileVolumeStore returns a new VolumeStore that keeps one file per volume
+// in the given directory. The directory is created if it does not exist.
+//
+// Files that are not valid volumes are skipped by List, and logged.
+func NewFileVolumeStore(dirPath string) (VolumeStore, error) {
+	return newFileVolumeStore(dirPath)
+}
+
 // VolumeDriverClient is a wrapper for APIClient.
 type VolumeDriverClient interface {
 	// Create a volume with the given name and opts.
@@ -66,16 +259,44 @@ type VolumeDriverClient interface {
 	Remove(name string) (err error)
 	// Get the path of the mountpoint for the given name.
 	Path(name string) (mountpoint string, err error)
-	// Mount the given volume and return the mountpoint.
-	Mount(name string) (mountpoint string, err error)
-	// Unmount the given volume.
-	Unmount(name string) (err error)
+	// Mount the given volume on behalf of the caller with the given id and
+	// return the mountpoint. Every mount with an empty id needs its own unmount.
+	Mount(name string, id string) (mountpoint string, err error)
+	// Unmount the given volume on behalf of the caller with the given id.
+	Unmount(name string, id string) (err error)
+	// Get the volume with the given name as the docker volume plugin API
+	// returns it, with only Name, Mountpoint, CreatedAt and Status set.
+	Get(name string) (*Volume, error)
+	// List all volumes as the docker volume plugin API returns them, with
+	// only Name, Mountpoint and CreatedAt set.
+	List() ([]*Volume, error)
+	// Get the capabilities of the volume driver.
+	Capabilities() (*Capabilities, error)
 	// Cleanup all volumes.
 	Cleanup() ([]*Volume, error)
+	// Cleanup the volumes matching the request.
+	CleanupVolumes(request *CleanupRequest) ([]*Volume, error)
 	// Get a volume by name.
 	GetVolume(name string) (*Volume, error)
 	// List all volumes.
 	ListVolumes() ([]*Volume, error)
+	// List the volumes matching the request. Follow NextPageToken to get
+	// all volumes if the request has a PageSize.
+	ListVolumesPage(request *ListVolumesRequest) (*Volumes, error)
+	// Reconcile the volumes with docker and the volume driver, and repair
+	// the differences if repair is set.
+	Reconcile(repair bool) (*ReconcileResponse, error)
+	// List the names of the volumes of the volume driver.
+	ListDriverVolumes() ([]string, error)
+	// Get the status of a volume reported by the volume driver.
+	GetVolumeStatus(name string) (map[string]string, error)
+	// Snapshot a volume to a snapshot with the given name.
+	SnapshotVolume(name string, snapshotName string) error
+	// Resize a volume to the given size in bytes.
+	ResizeVolume(name string, sizeBytes uint64) error
+	// Watch calls eventFunc for every event until ctx is done, eventFunc
+	// returns an error, or the stream of events fails.
+	Watch(ctx context.Context, eventFunc func(*Event) error) error
 }
 
 // NewVolumeDriverClient creates a new VolumeDriverClient for the given APIClient.
@@ -83,9 +304,114 @@ func NewVolumeDriverClient(apiClient APIClient) VolumeDriverClient {
 	return newVolumeDriverClient(apiClient)
 }
 
+// PluginClient is a client for the docker volume plugin protocol, calling a
+// volume plugin as docker does, with JSON over HTTP. It works with any volume
+// plugin, not only the plugins based on this package.
+//
+// Volumes only have the fields of the docker volume plugin protocol, that is
+// Name, Mountpoint, CreatedAt and Status. Status values that are not strings
+// are JSON encoded.
+type PluginClient interface {
+	// Activate the plugin and return the subsystems it implements,
+	// for example "VolumeDriver".
+	Activate() (implements []string, err error)
+	// Create a volume with the given name and opts.
+	Create(name string, opts map[string]string) (err error)
+	// Remove the volume with the given name.
+	Remove(name string) (err error)
+	// Get the path of the mountpoint for the given name.
+	Path(name string) (mountpoint string, err error)
+	// Mount the given volume on behalf of the caller with the given id and
+	// return the mountpoint. Every mount with an empty id needs its own unmount.
+	Mount(name string, id string) (mountpoint string, err error)
+	// Unmount the given volume on behalf of the caller with the given id.
+	Unmount(name string, id string) (err error)
+	// Get the volume with the given name.
+	Get(name string) (*Volume, error)
+	// List all volumes.
+	List() ([]*Volume, error)
+	// Get the capabilities of the volume driver. Plugins that do not
+	// implement capabilities have the scope ScopeLocal.
+	Capabilities() (*Capabilities, error)
+}
+
+// PluginClientOptions are options for a PluginClient.
+type PluginClientOptions struct {
+	// Timeout is the timeout for each call to the plugin.
+	// If not set, the timeout is 30 seconds.
+	Timeout time.Duration
+}
+
+// NewPluginClient returns a new PluginClient for the plugin at the given
+// address, either unix:///path/to/socket or tcp://host:port.
+func NewPluginClient(address string, opts PluginClientOptions) (PluginClient, error) {
+	return newPluginClient(address, opts)
+}
+
+// APIServerOptions are options for an APIServer.
+//
+// CreateTimeout, RemoveTimeout, MountTimeout and UnmountTimeout only apply to
+// a VolumeDriver that implements ContextVolumeDriver. Calls to any other
+// VolumeDriver can not be interrupted, and run for as long as they take.
+type APIServerOptions struct {
+	// VolumeStore persists the state of the volumes. The volumes in the
+	// VolumeStore are loaded when the APIServer is created.
+	// If not set, NewMemoryVolumeStore() is used.
+	VolumeStore VolumeStore
+	// Capabilities are reported to docker through VolumeDriver.Capabilities.
+	// If not set, or if Scope is not set, the scope is ScopeLocal.
+	Capabilities *Capabilities
+	// DockerClient is used by Cleanup and Reconcile to call docker.
+	// If not set, docker.NewClientFromEnv() is called on every request.
+	DockerClient DockerClient
+	// CreateTimeout is the maximum duration of a call to create a volume,
+	// if the VolumeDriver implements ContextVolumeDriver.
+	// If not set, there is no timeout.
+	CreateTimeout time.Duration
+	// RemoveTimeout is the maximum duration of a call to remove a volume,
+	// if the VolumeDriver implements ContextVolumeDriver.
+	// If not set, there is no timeout.
+	RemoveTimeout time.Duration
+	// MountTimeout is the maximum duration of a call to mount a volume,
+	// if the VolumeDriver implements ContextVolumeDriver.
+	// If not set, there is no timeout.
+	MountTimeout time.Duration
+	// UnmountTimeout is the maximum duration of a call to unmount a volume,
+	// if the VolumeDriver implements ContextVolumeDriver.
+	// If not set, there is no timeout.
+	UnmountTimeout time.Duration
+	// StatusTimeout is the maximum duration of getting the status and the disk
+	// usage of a volume for Get and GetVolume. If not set, there is no timeout.
+	StatusTimeout time.Duration
+	// ReconcileOnStart is the request for a Reconcile when the APIServer is
+	// created, after the volumes in the VolumeStore are loaded. If Reconcile
+	// fails, creating the APIServer fails. If not set, there is no Reconcile on start.
+	ReconcileOnStart *ReconcileRequest
+}
+
 // NewAPIServer returns a new APIServer for the given VolumeDriver and name.
 func NewAPIServer(volumeDriver VolumeDriver, volumeDriverName string) APIServer {
-	return newAPIServer(volumeDriver, volumeDriverName)
+	// the in-memory VolumeStore and the default capabilities never fail
+	apiServer, _ := newAPIServer(volumeDriver, volumeDriverName, APIServerOptions{})
+	return apiServer
+}
+
+// NewAPIServerWithOptions returns a new APIServer for the given VolumeDriver, name and options.
+func NewAPIServerWithOptions(volumeDriver VolumeDriver, volumeDriverName string, opts APIServerOptions) (APIServer, error) {
+	apiServer, err := newAPIServer(volumeDriver, volumeDriverName, opts)
+	if err != nil {
+		return nil, err
+	}
+	return apiServer, nil
+}
+
+func validateScope(scope string) error {
+	switch scope {
+	case ScopeLocal, ScopeGlobal:
+		return nil
+	default:
+		return fmt.Errorf("dockervolume: invalid scope: %s", scope)
+	}
 }
 
 // NewTCPServer returns a new Server for TCP.
@@ -94,20 +420,72 @@ func NewTCPServer(
 	volumeDriverName string,
 	address string,
 	opts dockerplugin.ServerOptions,
+) dockerplugin.Server {
+	return newTCPServer(NewAPIServer(volumeDriver, volumeDriverName), volumeDriverName, address, opts)
+}
+
+// NewUnixServer returns a new Server for Unix sockets.
+func NewUnixServer(
+	volumeDriver VolumeDriver,
+	volumeDriverName string,
+	group string,
+	opts dockerplugin.ServerOptions,
+) dockerplugin.Server {
+	return newUnixServer(NewAPIServer(volumeDriver, volumeDriverName), volumeDriverName, group, opts)
+}
+
+// ServerOptions are options for a Server.
+type ServerOptions struct {
+	APIServerOptions
+	dockerplugin.ServerOptions
+}
+
+// NewTCPServerWithOptions returns a new Server for TCP with the given options.
+func NewTCPServerWithOptions(
+	volumeDriver VolumeDriver,
+	volumeDriverName string,
+	address string,
+	opts ServerOptions,
+) (dockerplugin.Server, error) {
+	apiServer, err := NewAPIServerWithOptions(volumeDriver, volumeDriverName, opts.APIServerOptions)
+	if err != nil {
+		return nil, err
+	}
+	return newTCPServer(apiServer, volumeDriverName, address, opts.ServerOptions), nil
+}
+
+// NewUnixServerWithOptions returns a new Server for Unix sockets with the given options.
+func NewUnixServerWithOptions(
+	volumeDriver VolumeDriver,
+	volumeDriverName string,
+	group string,
+	opts ServerOptions,
+) (dockerplugin.Server, error) {
+	apiServer, err := NewAPIServerWithOptions(volumeDriver, volumeDriverName, opts.APIServerOptions)
+	if err != nil {
+		return nil, err
+	}
+	return newUnixServer(apiServer, volumeDriverName, group, opts.ServerOptions), nil
+}
+
+func newTCPServer(
+	apiServer APIServer,
+	volumeDriverName string,
+	address string,
+	opts dockerplugin.ServerOptions,
 ) dockerplugin.Server {
 	return dockerplugin.NewTCPServer(
 		volumeDriverName,
 		[]string{"VolumeDriver"},
-		func(s *grpc.Server) { RegisterAPIServer(s, NewAPIServer(volumeDriver, volumeDriverName)) },
+		func(s *grpc.Server) { RegisterAPIServer(s, apiServer) },
 		RegisterAPIHandler,
 		address,
 		opts,
 	)
 }
 
-// NewUnixServer returns a new Server for Unix sockets.
-func NewUnixServer(
-	volumeDriver VolumeDriver,
+func newUnixServer(
+	apiServer APIServer,
 	volumeDriverName string,
 	group string,
 	opts dockerplugin.ServerOptions,
@@ -115,7 +493,7 @@ func NewUnixServer(
 	return dockerplugin.NewUnixServer(
 		volumeDriverName,
 		[]string{"VolumeDriver"},
-		func(s *grpc.Server) { RegisterAPIServer(s, NewAPIServer(volumeDriver, volumeDriverName)) },
+		func(s *grpc.Server) { RegisterAPIServer(s, apiServer) },
 		RegisterAPIHandler,
 		group,
 		opts,
@@ -10,6 +10,7 @@ import math "math"
 
 // discarding unused import google_api1 "google/api"
 import google_protobuf1 "go.pedge.io/google-protobuf"
+import google_protobuf2 "go.pedge.io/google-protobuf"
 
 import (
 	context "golang.org/x/net/context"
@@ -21,11 +22,80 @@ var _ = proto.Marshal
 var _ = fmt.Errorf
 var _ = math.Inf
 
+// EventType is the type of an Event.
+type EventType int32
+
+const (
+	EventType_EVENT_TYPE_NONE    EventType = 0
+	EventType_EVENT_TYPE_CREATE  EventType = 1
+	EventType_EVENT_TYPE_REMOVE  EventType = 2
+	EventType_EVENT_TYPE_MOUNT   EventType = 3
+	EventType_EVENT_TYPE_UNMOUNT EventType = 4
+	EventType_EVENT_TYPE_CLEANUP EventType = 5
+)
+
+var EventType_name = map[int32]string{
+	0: "EVENT_TYPE_NONE",
+	1: "EVENT_TYPE_CREATE",
+	2: "EVENT_TYPE_REMOVE",
+	3: "EVENT_TYPE_MOUNT",
+	4: "EVENT_TYPE_UNMOUNT",
+	5: "EVENT_TYPE_CLEANUP",
+}
+var EventType_value = map[string]int32{
+	"EVENT_TYPE_NONE":    0,
+	"EVENT_TYPE_CREATE":  1,
+	"EVENT_TYPE_REMOVE":  2,
+	"EVENT_TYPE_MOUNT":   3,
+	"EVENT_TYPE_UNMOUNT": 4,
+	"EVENT_TYPE_CLEANUP": 5,
+}
+
+func (x EventType) String() string {
+	return proto.EnumName(EventType_name, int32(x))
+}
+
+// MountState is whether a volume is mounted.
+type MountState int32
+
+const (
+	MountState_MOUNT_STATE_ANY       MountState = 0
+	MountState_MOUNT_STATE_MOUNTED   MountState = 1
+	MountState_MOUNT_STATE_UNMOUNTED MountState = 2
+)
+
+var MountState_name = map[int32]string{
+	0: "MOUNT_STATE_ANY",
+	1: "MOUNT_STATE_MOUNTED",
+	2: "MOUNT_STATE_UNMOUNTED",
+}
+var MountState_value = map[string]int32{
+	"MOUNT_STATE_ANY":       0,
+	"MOUNT_STATE_MOUNTED":   1,
+	"MOUNT_STATE_UNMOUNTED": 2,
+}
+
+func (x MountState) String() string {
+	return proto.EnumName(MountState_name, int32(x))
+}
+
 // Volume represents a volume managed by the dockervolume package.
 type Volume struct {
 	Name       string            `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
 	Opts       map[string]string `protobuf:"bytes,2,rep,name=opts" json:"opts,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	Mountpoint string            `protobuf:"bytes,3,opt,name=mountpoint" json:"mountpoint,omitempty"`
+	// mount_ids are the ids of the callers that currently have the volume mounted.
+	// Every mount of a caller without an id has its own empty id.
+	MountIds []string `protobuf:"bytes,4,rep,name=mount_ids" json:"mount_ids,omitempty"`
+	// created_at is when the volume was created.
+	CreatedAt *google_protobuf2.Timestamp `protobuf:"bytes,5,opt,name=created_at" json:"created_at,omitempty"`
+	// status is the status reported by the volume driver, if it implements
+	// VolumeDriverStatusReporter. Only set by Get and GetVolume.
+	Status map[string]string `protobuf:"bytes,6,rep,name=status" json:"status,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// usage is the disk usage of the whole filesystem of the mountpoint, not
+	// of the volume alone, if the volume is mounted on a local path. Only set
+	// by Get and GetVolume.
+	Usage *DiskUsage `protobuf:"bytes,7,opt,name=usage" json:"usage,omitempty"`
 }
 
 func (m *Volume) Reset()         { *m = Volume{} }
@@ -39,9 +109,43 @@ func (m *Volume) GetOpts() map[string]string {
 	return nil
 }
 
+func (m *Volume) GetCreatedAt() *google_protobuf2.Timestamp {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return nil
+}
+
+func (m *Volume) GetStatus() map[string]string {
+	if m != nil {
+		return m.Status
+	}
+	return nil
+}
+
+func (m *Volume) GetUsage() *DiskUsage {
+	if m != nil {
+		return m.Usage
+	}
+	return nil
+}
+
+// DiskUsage is the disk usage of a filesystem.
+type DiskUsage struct {
+	TotalBytes     uint64 `protobuf:"varint,1,opt,name=total_bytes" json:"total_bytes,omitempty"`
+	UsedBytes      uint64 `protobuf:"varint,2,opt,name=used_bytes" json:"used_bytes,omitempty"`
+	AvailableBytes uint64 `protobuf:"varint,3,opt,name=available_bytes" json:"available_bytes,omitempty"`
+}
+
+func (m *DiskUsage) Reset()         { *m = DiskUsage{} }
+func (m *DiskUsage) String() string { return proto.CompactTextString(m) }
+func (*DiskUsage) ProtoMessage()    {}
+
 // Volumes is the plural of Volume.
 type Volumes struct {
 	Volume []*Volume `protobuf:"bytes,1,rep,name=volume" json:"volume,omitempty"`
+	// next_page_token is set by ListVolumes if there are more volumes.
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token" json:"next_page_token,omitempty"`
 }
 
 func (m *Volumes) Reset()         { *m = Volumes{} }
@@ -55,6 +159,38 @@ func (m *Volumes) GetVolume() []*Volume {
 	return nil
 }
 
+// ListVolumesRequest is a request to list the volumes matching all of the
+// given filters, sorted by name.
+type ListVolumesRequest struct {
+	// name_prefix only lists the volumes whose name starts with it.
+	NamePrefix string `protobuf:"bytes,1,opt,name=name_prefix" json:"name_prefix,omitempty"`
+	// selector only lists the volumes whose opts match every selector, either
+	// "key=value" for a key with a value, or "key" for a key with any value.
+	// A key can only be in one selector.
+	Selector []string `protobuf:"bytes,2,rep,name=selector" json:"selector,omitempty"`
+	// mount_state only lists the volumes in that state.
+	MountState MountState `protobuf:"varint,3,opt,name=mount_state,enum=dockervolume.MountState" json:"mount_state,omitempty"`
+	// page_size is the maximum number of volumes to return. If not set, all
+	// volumes are returned.
+	PageSize uint32 `protobuf:"varint,4,opt,name=page_size" json:"page_size,omitempty"`
+	// page_token is the next_page_token of the previous response, to get the next page.
+	PageToken string `protobuf:"bytes,5,opt,name=page_token" json:"page_token,omitempty"`
+}
+
+func (m *ListVolumesRequest) Reset()         { *m = ListVolumesRequest{} }
+func (m *ListVolumesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListVolumesRequest) ProtoMessage()    {}
+
+// Capabilities are the capabilities of a volume driver.
+type Capabilities struct {
+	// scope is either "local" or "global".
+	Scope string `protobuf:"bytes,1,opt,name=scope" json:"scope,omitempty"`
+}
+
+func (m *Capabilities) Reset()         { *m = Capabilities{} }
+func (m *Capabilities) String() string { return proto.CompactTextString(m) }
+func (*Capabilities) ProtoMessage()    {}
+
 // NameOptsRequest is a request with a volume name and opts.
 type NameOptsRequest struct {
 	Name string            `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
@@ -81,6 +217,16 @@ func (m *NameRequest) Reset()         { *m = NameRequest{} }
 func (m *NameRequest) String() string { return proto.CompactTextString(m) }
 func (*NameRequest) ProtoMessage()    {}
 
+// NameIDRequest is a request with a volume name and the id of the caller.
+type NameIDRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Id   string `protobuf:"bytes,2,opt,name=id" json:"id,omitempty"`
+}
+
+func (m *NameIDRequest) Reset()         { *m = NameIDRequest{} }
+func (m *NameIDRequest) String() string { return proto.CompactTextString(m) }
+func (*NameIDRequest) ProtoMessage()    {}
+
 // ErrResponse is a response for the docker volume plugin API with a potential error.
 type ErrResponse struct {
 	Err string `protobuf:"bytes,1,opt,name=err" json:"err,omitempty"`
@@ -100,6 +246,209 @@ func (m *MountpointErrResponse) Reset()         { *m = MountpointErrResponse{} }
 func (m *MountpointErrResponse) String() string { return proto.CompactTextString(m) }
 func (*MountpointErrResponse) ProtoMessage()    {}
 
+// PluginVolume is a volume as the docker volume plugin API returns it.
+//
+// The fields are named like the keys of the JSON that docker expects, as the
+// HTTP handler uses the field names as JSON keys.
+type PluginVolume struct {
+	Name       string `protobuf:"bytes,1,opt,name=Name" json:"Name,omitempty"`
+	Mountpoint string `protobuf:"bytes,2,opt,name=Mountpoint" json:"Mountpoint,omitempty"`
+	// CreatedAt is when the volume was created, in RFC 3339 format.
+	CreatedAt string            `protobuf:"bytes,3,opt,name=CreatedAt" json:"CreatedAt,omitempty"`
+	Status    map[string]string `protobuf:"bytes,4,rep,name=Status" json:"Status,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+}
+
+func (m *PluginVolume) Reset()         { *m = PluginVolume{} }
+func (m *PluginVolume) String() string { return proto.CompactTextString(m) }
+func (*PluginVolume) ProtoMessage()    {}
+
+func (m *PluginVolume) GetStatus() map[string]string {
+	if m != nil {
+		return m.Status
+	}
+	return nil
+}
+
+// VolumeErrResponse is a response for the docker volume plugin API with a volume and a potential error.
+type VolumeErrResponse struct {
+	Volume *PluginVolume `protobuf:"bytes,1,opt,name=volume" json:"volume,omitempty"`
+	Err    string        `protobuf:"bytes,2,opt,name=err" json:"err,omitempty"`
+}
+
+func (m *VolumeErrResponse) Reset()         { *m = VolumeErrResponse{} }
+func (m *VolumeErrResponse) String() string { return proto.CompactTextString(m) }
+func (*VolumeErrResponse) ProtoMessage()    {}
+
+func (m *VolumeErrResponse) GetVolume() *PluginVolume {
+	if m != nil {
+		return m.Volume
+	}
+	return nil
+}
+
+// VolumesErrResponse is a response for the docker volume plugin API with volumes and a potential error.
+type VolumesErrResponse struct {
+	Volumes []*PluginVolume `protobuf:"bytes,1,rep,name=volumes" json:"volumes,omitempty"`
+	Err     string          `protobuf:"bytes,2,opt,name=err" json:"err,omitempty"`
+}
+
+func (m *VolumesErrResponse) Reset()         { *m = VolumesErrResponse{} }
+func (m *VolumesErrResponse) String() string { return proto.CompactTextString(m) }
+func (*VolumesErrResponse) ProtoMessage()    {}
+
+func (m *VolumesErrResponse) GetVolumes() []*PluginVolume {
+	if m != nil {
+		return m.Volumes
+	}
+	return nil
+}
+
+// CapabilitiesResponse is a response for the docker volume plugin API with the capabilities of the volume driver.
+type CapabilitiesResponse struct {
+	Capabilities *Capabilities `protobuf:"bytes,1,opt,name=capabilities" json:"capabilities,omitempty"`
+}
+
+func (m *CapabilitiesResponse) Reset()         { *m = CapabilitiesResponse{} }
+func (m *CapabilitiesResponse) String() string { return proto.CompactTextString(m) }
+func (*CapabilitiesResponse) ProtoMessage()    {}
+
+func (m *CapabilitiesResponse) GetCapabilities() *Capabilities {
+	if m != nil {
+		return m.Capabilities
+	}
+	return nil
+}
+
+// CleanupRequest is a request to remove the volumes managed by the API that
+// docker knows about and that match all of the given filters.
+type CleanupRequest struct {
+	// dry_run only returns the volumes that would be removed.
+	DryRun bool `protobuf:"varint,1,opt,name=dry_run" json:"dry_run,omitempty"`
+	// name_prefix only removes the volumes whose name starts with it.
+	NamePrefix string `protobuf:"bytes,2,opt,name=name_prefix" json:"name_prefix,omitempty"`
+	// selector only removes the volumes whose opts match every selector, as in ListVolumesRequest.
+	Selector []string `protobuf:"bytes,3,rep,name=selector" json:"selector,omitempty"`
+	// only_unused skips the volumes referenced by a container, running or not.
+	OnlyUnused bool `protobuf:"varint,4,opt,name=only_unused" json:"only_unused,omitempty"`
+	// force unmounts the volumes for every caller before removing them.
+	Force bool `protobuf:"varint,5,opt,name=force" json:"force,omitempty"`
+}
+
+func (m *CleanupRequest) Reset()         { *m = CleanupRequest{} }
+func (m *CleanupRequest) String() string { return proto.CompactTextString(m) }
+func (*CleanupRequest) ProtoMessage()    {}
+
+// ReconcileRequest is a request to reconcile the volumes managed by the API
+// with docker and the volume driver.
+type ReconcileRequest struct {
+	// repair the differences that can be repaired, instead of only reporting them.
+	Repair bool `protobuf:"varint,1,opt,name=repair" json:"repair,omitempty"`
+}
+
+func (m *ReconcileRequest) Reset()         { *m = ReconcileRequest{} }
+func (m *ReconcileRequest) String() string { return proto.CompactTextString(m) }
+func (*ReconcileRequest) ProtoMessage()    {}
+
+// ReconcileResponse reports the differences found when reconciling.
+type ReconcileResponse struct {
+	// missing_in_docker are the volumes managed by the API that docker does not know about.
+	MissingInDocker []string `protobuf:"bytes,1,rep,name=missing_in_docker" json:"missing_in_docker,omitempty"`
+	// missing_in_api are the volumes of the volume driver that docker knows about,
+	// but that are not managed by the API. They are repaired by managing them again, without opts,
+	// if the volume driver does not implement VolumeDriverLister or lists them.
+	MissingInApi []string `protobuf:"bytes,2,rep,name=missing_in_api" json:"missing_in_api,omitempty"`
+	// missing_in_driver are the volumes managed by the API that the volume driver does not list.
+	// They are repaired by no longer managing them.
+	MissingInDriver []string `protobuf:"bytes,3,rep,name=missing_in_driver" json:"missing_in_driver,omitempty"`
+	// driver_orphans are the volumes the volume driver lists that neither docker nor the API know about.
+	DriverOrphans []string `protobuf:"bytes,4,rep,name=driver_orphans" json:"driver_orphans,omitempty"`
+	// stale_mountpoints are the volumes whose mountpoint does not exist anymore.
+	// They are repaired by marking them as not mounted.
+	StaleMountpoints []string `protobuf:"bytes,5,rep,name=stale_mountpoints" json:"stale_mountpoints,omitempty"`
+	// repaired are the volumes that were repaired.
+	Repaired []string `protobuf:"bytes,6,rep,name=repaired" json:"repaired,omitempty"`
+}
+
+func (m *ReconcileResponse) Reset()         { *m = ReconcileResponse{} }
+func (m *ReconcileResponse) String() string { return proto.CompactTextString(m) }
+func (*ReconcileResponse) ProtoMessage()    {}
+
+// Names is a list of volume names.
+type Names struct {
+	Name []string `protobuf:"bytes,1,rep,name=name" json:"name,omitempty"`
+}
+
+func (m *Names) Reset()         { *m = Names{} }
+func (m *Names) String() string { return proto.CompactTextString(m) }
+func (*Names) ProtoMessage()    {}
+
+// VolumeStatus is the status of a volume reported by the volume driver.
+type VolumeStatus struct {
+	Status map[string]string `protobuf:"bytes,1,rep,name=status" json:"status,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+}
+
+func (m *VolumeStatus) Reset()         { *m = VolumeStatus{} }
+func (m *VolumeStatus) String() string { return proto.CompactTextString(m) }
+func (*VolumeStatus) ProtoMessage()    {}
+
+func (m *VolumeStatus) GetStatus() map[string]string {
+	if m != nil {
+		return m.Status
+	}
+	return nil
+}
+
+// SnapshotRequest is a request to snapshot a volume.
+type SnapshotRequest struct {
+	Name         string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	SnapshotName string `protobuf:"bytes,2,opt,name=snapshot_name" json:"snapshot_name,omitempty"`
+}
+
+func (m *SnapshotRequest) Reset()         { *m = SnapshotRequest{} }
+func (m *SnapshotRequest) String() string { return proto.CompactTextString(m) }
+func (*SnapshotRequest) ProtoMessage()    {}
+
+// ResizeRequest is a request to resize a volume.
+type ResizeRequest struct {
+	Name      string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	SizeBytes uint64 `protobuf:"varint,2,opt,name=size_bytes" json:"size_bytes,omitempty"`
+}
+
+func (m *ResizeRequest) Reset()         { *m = ResizeRequest{} }
+func (m *ResizeRequest) String() string { return proto.CompactTextString(m) }
+func (*ResizeRequest) ProtoMessage()    {}
+
+// Event is a change to a volume managed by the API.
+type Event struct {
+	Type EventType `protobuf:"varint,1,opt,name=type,enum=dockervolume.EventType" json:"type,omitempty"`
+	// volume is the volume after the change, or before the change if it failed
+	// or if the volume was removed.
+	Volume    *Volume                     `protobuf:"bytes,2,opt,name=volume" json:"volume,omitempty"`
+	Timestamp *google_protobuf2.Timestamp `protobuf:"bytes,3,opt,name=timestamp" json:"timestamp,omitempty"`
+	// id is the id of the caller for mount and unmount events.
+	Id string `protobuf:"bytes,4,opt,name=id" json:"id,omitempty"`
+	// err is set if the change failed.
+	Err string `protobuf:"bytes,5,opt,name=err" json:"err,omitempty"`
+}
+
+func (m *Event) Reset()         { *m = Event{} }
+func (m *Event) String() string { return proto.CompactTextString(m) }
+func (*Event) ProtoMessage()    {}
+
+func (m *Event) GetVolume() *Volume {
+	if m != nil {
+		return m.Volume
+	}
+	return nil
+}
+
+func (m *Event) GetTimestamp() *google_protobuf2.Timestamp {
+	if m != nil {
+		return m.Timestamp
+	}
+	return nil
+}
+
 // Reference imports to suppress errors if they are not otherwise used.
 var _ context.Context
 var _ grpc.ClientConn
@@ -114,18 +463,44 @@ type APIClient interface {
 	// Path is the path function call for the docker volume plugin API.
 	Path(ctx context.Context, in *NameRequest, opts ...grpc.CallOption) (*MountpointErrResponse, error)
 	// Mount is the mount function call for the docker volume plugin API.
-	Mount(ctx context.Context, in *NameRequest, opts ...grpc.CallOption) (*MountpointErrResponse, error)
+	Mount(ctx context.Context, in *NameIDRequest, opts ...grpc.CallOption) (*MountpointErrResponse, error)
 	// Unmount is the unmount function call for the docker volume plugin API.
-	Unmount(ctx context.Context, in *NameRequest, opts ...grpc.CallOption) (*ErrResponse, error)
-	// Cleanup attempts to remove all volumes managed by the API. If any volume
-	// cannot be removed, for example if it is still attached to a container, this
-	// function will error. This function returns all volumes that were attempted
-	// to be removed.
-	Cleanup(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*Volumes, error)
+	Unmount(ctx context.Context, in *NameIDRequest, opts ...grpc.CallOption) (*ErrResponse, error)
+	// Get is the get function call for the docker volume plugin API.
+	Get(ctx context.Context, in *NameRequest, opts ...grpc.CallOption) (*VolumeErrResponse, error)
+	// List is the list function call for the docker volume plugin API.
+	List(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*VolumesErrResponse, error)
+	// Capabilities is the capabilities function call for the docker volume plugin API.
+	Capabilities(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*CapabilitiesResponse, error)
+	// Cleanup attempts to remove the volumes managed by the API that match the
+	// request. If any volume cannot be removed, for example if it is still
+	// attached to a container, this function will error. This function returns
+	// all volumes that were attempted to be removed, or that would be with dry_run.
+	Cleanup(ctx context.Context, in *CleanupRequest, opts ...grpc.CallOption) (*Volumes, error)
 	// GetVolume returns the volume managed by the API.
 	GetVolume(ctx context.Context, in *NameRequest, opts ...grpc.CallOption) (*Volume, error)
-	// ListVolumes returns all volumes managed by the API.
-	ListVolumes(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*Volumes, error)
+	// ListVolumes returns the volumes managed by the API that match the request.
+	ListVolumes(ctx context.Context, in *ListVolumesRequest, opts ...grpc.CallOption) (*Volumes, error)
+	// Reconcile compares the volumes managed by the API with the volumes docker
+	// knows about for this volume driver and, if the volume driver implements
+	// VolumeDriverLister, with the volumes of the volume driver.
+	Reconcile(ctx context.Context, in *ReconcileRequest, opts ...grpc.CallOption) (*ReconcileResponse, error)
+	// ListDriverVolumes returns the names of the volumes of the volume driver,
+	// if the volume driver implements VolumeDriverLister.
+	ListDriverVolumes(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*Names, error)
+	// GetVolumeStatus returns the status of a volume, if the volume driver
+	// implements VolumeDriverStatusReporter.
+	GetVolumeStatus(ctx context.Context, in *NameRequest, opts ...grpc.CallOption) (*VolumeStatus, error)
+	// SnapshotVolume snapshots a volume, if the volume driver implements
+	// VolumeDriverSnapshotter.
+	SnapshotVolume(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*google_protobuf1.Empty, error)
+	// ResizeVolume resizes a volume, if the volume driver implements
+	// VolumeDriverResizer.
+	ResizeVolume(ctx context.Context, in *ResizeRequest, opts ...grpc.CallOption) (*google_protobuf1.Empty, error)
+	// Watch streams the events for the volumes managed by the API, starting
+	// with the first event after the call. A watcher that does not keep up with
+	// the events is disconnected.
+	Watch(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (API_WatchClient, error)
 }
 
 type aPIClient struct {
@@ -163,7 +538,7 @@ func (c *aPIClient) Path(ctx context.Context, in *NameRequest, opts ...grpc.Call
 	return out, nil
 }
 
-func (c *aPIClient) Mount(ctx context.Context, in *NameRequest, opts ...grpc.CallOption) (*MountpointErrResponse, error) {
+func (c *aPIClient) Mount(ctx context.Context, in *NameIDRequest, opts ...grpc.CallOption) (*MountpointErrResponse, error) {
 	out := new(MountpointErrResponse)
 	err := grpc.Invoke(ctx, "/dockervolume.API/Mount", in, out, c.cc, opts...)
 	if err != nil {
@@ -172,7 +547,7 @@ func (c *aPIClient) Mount(ctx context.Context, in *NameRequest, opts ...grpc.Cal
 	return out, nil
 }
 
-func (c *aPIClient) Unmount(ctx context.Context, in *NameRequest, opts ...grpc.CallOption) (*ErrResponse, error) {
+func (c *aPIClient) Unmount(ctx context.Context, in *NameIDRequest, opts ...grpc.CallOption) (*ErrResponse, error) {
 	out := new(ErrResponse)
 	err := grpc.Invoke(ctx, "/dockervolume.API/Unmount", in, out, c.cc, opts...)
 	if err != nil {
@@ -181,7 +556,34 @@ func (c *aPIClient) Unmount(ctx context.Context, in *NameRequest, opts ...grpc.C
 	return out, nil
 }
 
-func (c *aPIClient) Cleanup(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*Volumes, error) {
+func (c *aPIClient) Get(ctx context.Context, in *NameRequest, opts ...grpc.CallOption) (*VolumeErrResponse, error) {
+	out := new(VolumeErrResponse)
+	err := grpc.Invoke(ctx, "/dockervolume.API/Get", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) List(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*VolumesErrResponse, error) {
+	out := new(VolumesErrResponse)
+	err := grpc.Invoke(ctx, "/dockervolume.API/List", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) Capabilities(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*CapabilitiesResponse, error) {
+	out := new(CapabilitiesResponse)
+	err := grpc.Invoke(ctx, "/dockervolume.API/Capabilities", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) Cleanup(ctx context.Context, in *CleanupRequest, opts ...grpc.CallOption) (*Volumes, error) {
 	out := new(Volumes)
 	err := grpc.Invoke(ctx, "/dockervolume.API/Cleanup", in, out, c.cc, opts...)
 	if err != nil {
@@ -199,7 +601,7 @@ func (c *aPIClient) GetVolume(ctx context.Context, in *NameRequest, opts ...grpc
 	return out, nil
 }
 
-func (c *aPIClient) ListVolumes(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*Volumes, error) {
+func (c *aPIClient) ListVolumes(ctx context.Context, in *ListVolumesRequest, opts ...grpc.CallOption) (*Volumes, error) {
 	out := new(Volumes)
 	err := grpc.Invoke(ctx, "/dockervolume.API/ListVolumes", in, out, c.cc, opts...)
 	if err != nil {
@@ -208,6 +610,83 @@ func (c *aPIClient) ListVolumes(ctx context.Context, in *google_protobuf1.Empty,
 	return out, nil
 }
 
+func (c *aPIClient) Reconcile(ctx context.Context, in *ReconcileRequest, opts ...grpc.CallOption) (*ReconcileResponse, error) {
+	out := new(ReconcileResponse)
+	err := grpc.Invoke(ctx, "/dockervolume.API/Reconcile", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) ListDriverVolumes(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*Names, error) {
+	out := new(Names)
+	err := grpc.Invoke(ctx, "/dockervolume.API/ListDriverVolumes", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) GetVolumeStatus(ctx context.Context, in *NameRequest, opts ...grpc.CallOption) (*VolumeStatus, error) {
+	out := new(VolumeStatus)
+	err := grpc.Invoke(ctx, "/dockervolume.API/GetVolumeStatus", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) SnapshotVolume(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*google_protobuf1.Empty, error) {
+	out := new(google_protobuf1.Empty)
+	err := grpc.Invoke(ctx, "/dockervolume.API/SnapshotVolume", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) ResizeVolume(ctx context.Context, in *ResizeRequest, opts ...grpc.CallOption) (*google_protobuf1.Empty, error) {
+	out := new(google_protobuf1.Empty)
+	err := grpc.Invoke(ctx, "/dockervolume.API/ResizeVolume", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) Watch(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (API_WatchClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_API_serviceDesc.Streams[0], c.cc, "/dockervolume.API/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &aPIWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type API_WatchClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type aPIWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *aPIWatchClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // Server API for API service
 
 type APIServer interface {
@@ -218,18 +697,44 @@ type APIServer interface {
 	// Path is the path function call for the docker volume plugin API.
 	Path(context.Context, *NameRequest) (*MountpointErrResponse, error)
 	// Mount is the mount function call for the docker volume plugin API.
-	Mount(context.Context, *NameRequest) (*MountpointErrResponse, error)
+	Mount(context.Context, *NameIDRequest) (*MountpointErrResponse, error)
 	// Unmount is the unmount function call for the docker volume plugin API.
-	Unmount(context.Context, *NameRequest) (*ErrResponse, error)
-	// Cleanup attempts to remove all volumes managed by the API. If any volume
-	// cannot be removed, for example if it is still attached to a container, this
-	// function will error. This function returns all volumes that were attempted
-	// to be removed.
-	Cleanup(context.Context, *google_protobuf1.Empty) (*Volumes, error)
+	Unmount(context.Context, *NameIDRequest) (*ErrResponse, error)
+	// Get is the get function call for the docker volume plugin API.
+	Get(context.Context, *NameRequest) (*VolumeErrResponse, error)
+	// List is the list function call for the docker volume plugin API.
+	List(context.Context, *google_protobuf1.Empty) (*VolumesErrResponse, error)
+	// Capabilities is the capabilities function call for the docker volume plugin API.
+	Capabilities(context.Context, *google_protobuf1.Empty) (*CapabilitiesResponse, error)
+	// Cleanup attempts to remove the volumes managed by the API that match the
+	// request. If any volume cannot be removed, for example if it is still
+	// attached to a container, this function will error. This function returns
+	// all volumes that were attempted to be removed, or that would be with dry_run.
+	Cleanup(context.Context, *CleanupRequest) (*Volumes, error)
 	// GetVolume returns the volume managed by the API.
 	GetVolume(context.Context, *NameRequest) (*Volume, error)
-	// ListVolumes returns all volumes managed by the API.
-	ListVolumes(context.Context, *google_protobuf1.Empty) (*Volumes, error)
+	// ListVolumes returns the volumes managed by the API that match the request.
+	ListVolumes(context.Context, *ListVolumesRequest) (*Volumes, error)
+	// Reconcile compares the volumes managed by the API with the volumes docker
+	// knows about for this volume driver and, if the volume driver implements
+	// VolumeDriverLister, with the volumes of the volume driver.
+	Reconcile(context.Context, *ReconcileRequest) (*ReconcileResponse, error)
+	// ListDriverVolumes returns the names of the volumes of the volume driver,
+	// if the volume driver implements VolumeDriverLister.
+	ListDriverVolumes(context.Context, *google_protobuf1.Empty) (*Names, error)
+	// GetVolumeStatus returns the status of a volume, if the volume driver
+	// implements VolumeDriverStatusReporter.
+	GetVolumeStatus(context.Context, *NameRequest) (*VolumeStatus, error)
+	// SnapshotVolume snapshots a volume, if the volume driver implements
+	// VolumeDriverSnapshotter.
+	SnapshotVolume(context.Context, *SnapshotRequest) (*google_protobuf1.Empty, error)
+	// ResizeVolume resizes a volume, if the volume driver implements
+	// VolumeDriverResizer.
+	ResizeVolume(context.Context, *ResizeRequest) (*google_protobuf1.Empty, error)
+	// Watch streams the events for the volumes managed by the API, starting
+	// with the first event after the call. A watcher that does not keep up with
+	// the events is disconnected.
+	Watch(*google_protobuf1.Empty, API_WatchServer) error
 }
 
 func RegisterAPIServer(s *grpc.Server, srv APIServer) {
@@ -273,7 +778,7 @@ func _API_Path_Handler(srv interface{}, ctx context.Context, dec func(interface{
 }
 
 func _API_Mount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
-	in := new(NameRequest)
+	in := new(NameIDRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
@@ -285,7 +790,7 @@ func _API_Mount_Handler(srv interface{}, ctx context.Context, dec func(interface
 }
 
 func _API_Unmount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
-	in := new(NameRequest)
+	in := new(NameIDRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
@@ -296,11 +801,47 @@ func _API_Unmount_Handler(srv interface{}, ctx context.Context, dec func(interfa
 	return out, nil
 }
 
-func _API_Cleanup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+func _API_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(NameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(APIServer).Get(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _API_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(google_protobuf1.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(APIServer).List(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _API_Capabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
 	in := new(google_protobuf1.Empty)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
+	out, err := srv.(APIServer).Capabilities(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _API_Cleanup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(CleanupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
 	out, err := srv.(APIServer).Cleanup(ctx, in)
 	if err != nil {
 		return nil, err
@@ -321,7 +862,7 @@ func _API_GetVolume_Handler(srv interface{}, ctx context.Context, dec func(inter
 }
 
 func _API_ListVolumes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
-	in := new(google_protobuf1.Empty)
+	in := new(ListVolumesRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
@@ -332,6 +873,87 @@ func _API_ListVolumes_Handler(srv interface{}, ctx context.Context, dec func(int
 	return out, nil
 }
 
+func _API_Reconcile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(ReconcileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(APIServer).Reconcile(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _API_ListDriverVolumes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(google_protobuf1.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(APIServer).ListDriverVolumes(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _API_GetVolumeStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(NameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(APIServer).GetVolumeStatus(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _API_SnapshotVolume_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(SnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(APIServer).SnapshotVolume(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _API_ResizeVolume_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(ResizeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(APIServer).ResizeVolume(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _API_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(google_protobuf1.Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(APIServer).Watch(m, &aPIWatchServer{stream})
+}
+
+type API_WatchServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type aPIWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *aPIWatchServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 var _API_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "dockervolume.API",
 	HandlerType: (*APIServer)(nil),
@@ -356,6 +978,18 @@ var _API_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Unmount",
 			Handler:    _API_Unmount_Handler,
 		},
+		{
+			MethodName: "Get",
+			Handler:    _API_Get_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _API_List_Handler,
+		},
+		{
+			MethodName: "Capabilities",
+			Handler:    _API_Capabilities_Handler,
+		},
 		{
 			MethodName: "Cleanup",
 			Handler:    _API_Cleanup_Handler,
@@ -368,6 +1002,37 @@ var _API_serviceDesc = grpc.ServiceDesc{
 			MethodName: "ListVolumes",
 			Handler:    _API_ListVolumes_Handler,
 		},
+		{
+			MethodName: "Reconcile",
+			Handler:    _API_Reconcile_Handler,
+		},
+		{
+			MethodName: "ListDriverVolumes",
+			Handler:    _API_ListDriverVolumes_Handler,
+		},
+		{
+			MethodName: "GetVolumeStatus",
+			Handler:    _API_GetVolumeStatus_Handler,
+		},
+		{
+			MethodName: "SnapshotVolume",
+			Handler:    _API_SnapshotVolume_Handler,
+		},
+		{
+			MethodName: "ResizeVolume",
+			Handler:    _API_ResizeVolume_Handler,
+		},
 	},
-	Streams: []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _API_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+}
+
+func init() {
+	proto.RegisterEnum("dockervolume.MountState", MountState_name, MountState_value)
+	proto.RegisterEnum("dockervolume.EventType", EventType_name, EventType_value)
 }
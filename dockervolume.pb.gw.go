@@ -56,7 +56,7 @@ func request_API_Path_0(ctx context.Context, client APIClient, req *http.Request
 }
 
 func request_API_Mount_0(ctx context.Context, client APIClient, req *http.Request, pathParams map[string]string) (proto.Message, error) {
-	var protoReq NameRequest
+	var protoReq NameIDRequest
 
 	if err := json.NewDecoder(req.Body).Decode(&protoReq); err != nil {
 		return nil, grpc.Errorf(codes.InvalidArgument, "%v", err)
@@ -66,7 +66,7 @@ func request_API_Mount_0(ctx context.Context, client APIClient, req *http.Reques
 }
 
 func request_API_Unmount_0(ctx context.Context, client APIClient, req *http.Request, pathParams map[string]string) (proto.Message, error) {
-	var protoReq NameRequest
+	var protoReq NameIDRequest
 
 	if err := json.NewDecoder(req.Body).Decode(&protoReq); err != nil {
 		return nil, grpc.Errorf(codes.InvalidArgument, "%v", err)
@@ -75,9 +75,47 @@ func request_API_Unmount_0(ctx context.Context, client APIClient, req *http.Requ
 	return client.Unmount(ctx, &protoReq)
 }
 
-func request_API_Cleanup_0(ctx context.Context, client APIClient, req *http.Request, pathParams map[string]string) (proto.Message, error) {
+func request_API_Get_0(ctx context.Context, client APIClient, req *http.Request, pathParams map[string]string) (proto.Message, error) {
+	var protoReq NameRequest
+
+	if err := json.NewDecoder(req.Body).Decode(&protoReq); err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
+	return client.Get(ctx, &protoReq)
+}
+
+func request_API_List_0(ctx context.Context, client APIClient, req *http.Request, pathParams map[string]string) (proto.Message, error) {
+	var protoReq google_protobuf.Empty
+
+	if err := json.NewDecoder(req.Body).Decode(&protoReq); err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
+	return client.List(ctx, &protoReq)
+}
+
+func request_API_Capabilities_0(ctx context.Context, client APIClient, req *http.Request, pathParams map[string]string) (proto.Message, error) {
 	var protoReq google_protobuf.Empty
 
+	if err := json.NewDecoder(req.Body).Decode(&protoReq); err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
+	return client.Capabilities(ctx, &protoReq)
+}
+
+var (
+	filter_API_Cleanup_0 = &utilities.DoubleArray{Encoding: map[string]int{}, Base: []int(nil), Check: []int(nil)}
+)
+
+func request_API_Cleanup_0(ctx context.Context, client APIClient, req *http.Request, pathParams map[string]string) (proto.Message, error) {
+	var protoReq CleanupRequest
+
+	if err := runtime.PopulateQueryParameters(&protoReq, req.URL.Query(), filter_API_Cleanup_0); err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
 	return client.Cleanup(ctx, &protoReq)
 }
 
@@ -105,12 +143,116 @@ func request_API_GetVolume_0(ctx context.Context, client APIClient, req *http.Re
 	return client.GetVolume(ctx, &protoReq)
 }
 
+var (
+	filter_API_ListVolumes_0 = &utilities.DoubleArray{Encoding: map[string]int{}, Base: []int(nil), Check: []int(nil)}
+)
+
 func request_API_ListVolumes_0(ctx context.Context, client APIClient, req *http.Request, pathParams map[string]string) (proto.Message, error) {
-	var protoReq google_protobuf.Empty
+	var protoReq ListVolumesRequest
+
+	if err := runtime.PopulateQueryParameters(&protoReq, req.URL.Query(), filter_API_ListVolumes_0); err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "%v", err)
+	}
 
 	return client.ListVolumes(ctx, &protoReq)
 }
 
+func request_API_Reconcile_0(ctx context.Context, client APIClient, req *http.Request, pathParams map[string]string) (proto.Message, error) {
+	var protoReq ReconcileRequest
+
+	if err := json.NewDecoder(req.Body).Decode(&protoReq); err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
+	return client.Reconcile(ctx, &protoReq)
+}
+
+func request_API_ListDriverVolumes_0(ctx context.Context, client APIClient, req *http.Request, pathParams map[string]string) (proto.Message, error) {
+	var protoReq google_protobuf.Empty
+
+	return client.ListDriverVolumes(ctx, &protoReq)
+}
+
+func request_API_GetVolumeStatus_0(ctx context.Context, client APIClient, req *http.Request, pathParams map[string]string) (proto.Message, error) {
+	var protoReq NameRequest
+
+	var (
+		val string
+		ok  bool
+		err error
+		_   = err
+	)
+
+	val, ok = pathParams["name"]
+	if !ok {
+		return nil, grpc.Errorf(codes.InvalidArgument, "missing parameter %s", "name")
+	}
+
+	protoReq.Name, err = runtime.String(val)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return client.GetVolumeStatus(ctx, &protoReq)
+}
+
+func request_API_SnapshotVolume_0(ctx context.Context, client APIClient, req *http.Request, pathParams map[string]string) (proto.Message, error) {
+	var protoReq SnapshotRequest
+
+	if err := json.NewDecoder(req.Body).Decode(&protoReq); err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
+	var (
+		val string
+		ok  bool
+		err error
+		_   = err
+	)
+
+	val, ok = pathParams["name"]
+	if !ok {
+		return nil, grpc.Errorf(codes.InvalidArgument, "missing parameter %s", "name")
+	}
+
+	protoReq.Name, err = runtime.String(val)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return client.SnapshotVolume(ctx, &protoReq)
+}
+
+func request_API_ResizeVolume_0(ctx context.Context, client APIClient, req *http.Request, pathParams map[string]string) (proto.Message, error) {
+	var protoReq ResizeRequest
+
+	if err := json.NewDecoder(req.Body).Decode(&protoReq); err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
+	var (
+		val string
+		ok  bool
+		err error
+		_   = err
+	)
+
+	val, ok = pathParams["name"]
+	if !ok {
+		return nil, grpc.Errorf(codes.InvalidArgument, "missing parameter %s", "name")
+	}
+
+	protoReq.Name, err = runtime.String(val)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return client.ResizeVolume(ctx, &protoReq)
+}
+
 // RegisterAPIHandlerFromEndpoint is same as RegisterAPIHandler but
 // automatically dials to "endpoint" and closes the connection when "ctx" gets done.
 func RegisterAPIHandlerFromEndpoint(ctx context.Context, mux *runtime.ServeMux, endpoint string) (err error) {
@@ -196,6 +338,39 @@ func RegisterAPIHandler(ctx context.Context, mux *runtime.ServeMux, conn *grpc.C
 
 	})
 
+	mux.Handle("POST", pattern_API_Get_0, func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		resp, err := request_API_Get_0(runtime.AnnotateContext(ctx, req), client, req, pathParams)
+		if err != nil {
+			runtime.HTTPError(ctx, w, err)
+			return
+		}
+
+		forward_API_Get_0(ctx, w, req, resp, mux.GetForwardResponseOptions()...)
+
+	})
+
+	mux.Handle("POST", pattern_API_List_0, func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		resp, err := request_API_List_0(runtime.AnnotateContext(ctx, req), client, req, pathParams)
+		if err != nil {
+			runtime.HTTPError(ctx, w, err)
+			return
+		}
+
+		forward_API_List_0(ctx, w, req, resp, mux.GetForwardResponseOptions()...)
+
+	})
+
+	mux.Handle("POST", pattern_API_Capabilities_0, func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		resp, err := request_API_Capabilities_0(runtime.AnnotateContext(ctx, req), client, req, pathParams)
+		if err != nil {
+			runtime.HTTPError(ctx, w, err)
+			return
+		}
+
+		forward_API_Capabilities_0(ctx, w, req, resp, mux.GetForwardResponseOptions()...)
+
+	})
+
 	mux.Handle("GET", pattern_API_Cleanup_0, func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
 		resp, err := request_API_Cleanup_0(runtime.AnnotateContext(ctx, req), client, req, pathParams)
 		if err != nil {
@@ -229,6 +404,61 @@ func RegisterAPIHandler(ctx context.Context, mux *runtime.ServeMux, conn *grpc.C
 
 	})
 
+	mux.Handle("POST", pattern_API_Reconcile_0, func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		resp, err := request_API_Reconcile_0(runtime.AnnotateContext(ctx, req), client, req, pathParams)
+		if err != nil {
+			runtime.HTTPError(ctx, w, err)
+			return
+		}
+
+		forward_API_Reconcile_0(ctx, w, req, resp, mux.GetForwardResponseOptions()...)
+
+	})
+
+	mux.Handle("GET", pattern_API_ListDriverVolumes_0, func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		resp, err := request_API_ListDriverVolumes_0(runtime.AnnotateContext(ctx, req), client, req, pathParams)
+		if err != nil {
+			runtime.HTTPError(ctx, w, err)
+			return
+		}
+
+		forward_API_ListDriverVolumes_0(ctx, w, req, resp, mux.GetForwardResponseOptions()...)
+
+	})
+
+	mux.Handle("GET", pattern_API_GetVolumeStatus_0, func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		resp, err := request_API_GetVolumeStatus_0(runtime.AnnotateContext(ctx, req), client, req, pathParams)
+		if err != nil {
+			runtime.HTTPError(ctx, w, err)
+			return
+		}
+
+		forward_API_GetVolumeStatus_0(ctx, w, req, resp, mux.GetForwardResponseOptions()...)
+
+	})
+
+	mux.Handle("POST", pattern_API_SnapshotVolume_0, func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		resp, err := request_API_SnapshotVolume_0(runtime.AnnotateContext(ctx, req), client, req, pathParams)
+		if err != nil {
+			runtime.HTTPError(ctx, w, err)
+			return
+		}
+
+		forward_API_SnapshotVolume_0(ctx, w, req, resp, mux.GetForwardResponseOptions()...)
+
+	})
+
+	mux.Handle("POST", pattern_API_ResizeVolume_0, func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		resp, err := request_API_ResizeVolume_0(runtime.AnnotateContext(ctx, req), client, req, pathParams)
+		if err != nil {
+			runtime.HTTPError(ctx, w, err)
+			return
+		}
+
+		forward_API_ResizeVolume_0(ctx, w, req, resp, mux.GetForwardResponseOptions()...)
+
+	})
+
 	return nil
 }
 
@@ -243,11 +473,27 @@ var (
 
 	pattern_API_Unmount_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0}, []string{"VolumeDriver.Unmount"}, ""))
 
+	pattern_API_Get_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0}, []string{"VolumeDriver.Get"}, ""))
+
+	pattern_API_List_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0}, []string{"VolumeDriver.List"}, ""))
+
+	pattern_API_Capabilities_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0}, []string{"VolumeDriver.Capabilities"}, ""))
+
 	pattern_API_Cleanup_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1}, []string{"api", "cleanup"}, ""))
 
 	pattern_API_GetVolume_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1, 1, 0, 4, 1, 5, 2}, []string{"api", "volumes", "name"}, ""))
 
 	pattern_API_ListVolumes_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1}, []string{"api", "volumes"}, ""))
+
+	pattern_API_Reconcile_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1}, []string{"api", "reconcile"}, ""))
+
+	pattern_API_ListDriverVolumes_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1, 2, 2}, []string{"api", "driver", "volumes"}, ""))
+
+	pattern_API_GetVolumeStatus_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1, 1, 0, 4, 1, 5, 2, 2, 3}, []string{"api", "volumes", "name", "status"}, ""))
+
+	pattern_API_SnapshotVolume_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1, 1, 0, 4, 1, 5, 2, 2, 3}, []string{"api", "volumes", "name", "snapshot"}, ""))
+
+	pattern_API_ResizeVolume_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1, 1, 0, 4, 1, 5, 2, 2, 3}, []string{"api", "volumes", "name", "resize"}, ""))
 )
 
 var (
@@ -261,9 +507,25 @@ var (
 
 	forward_API_Unmount_0 = runtime.ForwardResponseMessage
 
+	forward_API_Get_0 = runtime.ForwardResponseMessage
+
+	forward_API_List_0 = runtime.ForwardResponseMessage
+
+	forward_API_Capabilities_0 = runtime.ForwardResponseMessage
+
 	forward_API_Cleanup_0 = runtime.ForwardResponseMessage
 
 	forward_API_GetVolume_0 = runtime.ForwardResponseMessage
 
 	forward_API_ListVolumes_0 = runtime.ForwardResponseMessage
+
+	forward_API_Reconcile_0 = runtime.ForwardResponseMessage
+
+	forward_API_ListDriverVolumes_0 = runtime.ForwardResponseMessage
+
+	forward_API_GetVolumeStatus_0 = runtime.ForwardResponseMessage
+
+	forward_API_SnapshotVolume_0 = runtime.ForwardResponseMessage
+
+	forward_API_ResizeVolume_0 = runtime.ForwardResponseMessage
 )
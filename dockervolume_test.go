@@ -1,14 +1,32 @@
 package dockervolume
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/fsouza/go-dockerclient"
+	gatewayruntime "github.com/gengo/grpc-gateway/runtime"
 	"github.com/stretchr/testify/require"
 
+	"go.pedge.io/google-protobuf"
 	"go.pedge.io/pkg/map"
 	"go.pedge.io/proto/test"
+	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 )
 
 const (
@@ -40,7 +58,7 @@ func testBasic(t *testing.T, fakeVolumeDriver *fakeVolumeDriver, client VolumeDr
 		},
 	)
 	fakeVolumeDriver.requireStatusEquals("foo", fakeStatusCreate)
-	mountpoint, err := client.Mount("foo")
+	mountpoint, err := client.Mount("foo", "container")
 	require.NoError(t, err)
 	require.Equal(t, "/mnt/foo", mountpoint)
 	requireVolumesEqual(
@@ -53,10 +71,11 @@ func testBasic(t *testing.T, fakeVolumeDriver *fakeVolumeDriver, client VolumeDr
 				"uint64": "1234",
 			},
 			Mountpoint: "/mnt/foo",
+			MountIds:   []string{"container"},
 		},
 	)
 	fakeVolumeDriver.requireStatusEquals("foo", fakeStatusMount)
-	err = client.Unmount("foo")
+	err = client.Unmount("foo", "container")
 	require.NoError(t, err)
 	requireVolumesEqual(
 		t,
@@ -74,42 +93,1125 @@ func testBasic(t *testing.T, fakeVolumeDriver *fakeVolumeDriver, client VolumeDr
 	err = client.Remove("foo")
 	require.NoError(t, err)
 	requireVolumesEqual(t, client)
+	_, err = client.Get("foo")
+	require.Error(t, err)
+}
+
+func TestSharedMount(t *testing.T) {
+	runTest(t, testSharedMount)
+}
+
+func testSharedMount(t *testing.T, fakeVolumeDriver *fakeVolumeDriver, client VolumeDriverClient) {
+	require.NoError(t, client.Create("foo", map[string]string{"key": "value"}))
+	mountpoint, err := client.Mount("foo", "a")
+	require.NoError(t, err)
+	require.Equal(t, "/mnt/foo", mountpoint)
+	mountpoint, err = client.Mount("foo", "b")
+	require.NoError(t, err)
+	require.Equal(t, "/mnt/foo", mountpoint)
+	// mounting again with the same id is a no-op
+	mountpoint, err = client.Mount("foo", "b")
+	require.NoError(t, err)
+	require.Equal(t, "/mnt/foo", mountpoint)
+	fakeVolumeDriver.requireNumMountsEquals("foo", 1)
+	requireVolumesEqual(
+		t,
+		client,
+		&Volume{
+			Name:       "foo",
+			Opts:       map[string]string{"key": "value"},
+			Mountpoint: "/mnt/foo",
+			MountIds:   []string{"a", "b"},
+		},
+	)
+	require.NoError(t, client.Unmount("foo", "a"))
+	require.Error(t, client.Unmount("foo", "a"))
+	fakeVolumeDriver.requireStatusEquals("foo", fakeStatusMount)
+	requireVolumesEqual(
+		t,
+		client,
+		&Volume{
+			Name:       "foo",
+			Opts:       map[string]string{"key": "value"},
+			Mountpoint: "/mnt/foo",
+			MountIds:   []string{"b"},
+		},
+	)
+	require.NoError(t, client.Unmount("foo", "b"))
+	fakeVolumeDriver.requireStatusEquals("foo", fakeStatusUnmount)
+	requireVolumesEqual(
+		t,
+		client,
+		&Volume{
+			Name: "foo",
+			Opts: map[string]string{"key": "value"},
+		},
+	)
+	require.Error(t, client.Unmount("foo", "b"))
+}
+
+func TestAnonymousMount(t *testing.T) {
+	runTest(
+		t,
+		func(t *testing.T, fakeVolumeDriver *fakeVolumeDriver, client VolumeDriverClient) {
+			require.NoError(t, client.Create("foo", map[string]string{"key": "value"}))
+			_, err := client.Mount("foo", "")
+			require.NoError(t, err)
+			_, err = client.Mount("foo", "")
+			require.NoError(t, err)
+			fakeVolumeDriver.requireNumMountsEquals("foo", 1)
+			// the second caller without an id still has the volume mounted
+			require.NoError(t, client.Unmount("foo", ""))
+			fakeVolumeDriver.requireStatusEquals("foo", fakeStatusMount)
+			requireVolumesEqual(
+				t,
+				client,
+				&Volume{
+					Name:       "foo",
+					Opts:       map[string]string{"key": "value"},
+					Mountpoint: "/mnt/foo",
+					MountIds:   []string{""},
+				},
+			)
+			require.NoError(t, client.Unmount("foo", ""))
+			fakeVolumeDriver.requireStatusEquals("foo", fakeStatusUnmount)
+			requireVolumeError(t, client.Unmount("foo", ""), "unmount", "foo", ErrNotMounted)
+		},
+	)
+}
+
+func TestIndependentVolumesInParallel(t *testing.T) {
+	runTest(t, testIndependentVolumesInParallel)
+}
+
+func testIndependentVolumesInParallel(t *testing.T, fakeVolumeDriver *fakeVolumeDriver, client VolumeDriverClient) {
+	require.NoError(t, client.Create("foo", map[string]string{"key": "value"}))
+	require.NoError(t, client.Create("bar", map[string]string{"key": "value"}))
+	blocked := fakeVolumeDriver.blockMount("foo")
+	errC := make(chan error, 1)
+	go func() {
+		_, err := client.Mount("foo", "container")
+		errC <- err
+	}()
+	<-blocked
+	// foo is still being mounted, but bar and read-only calls must not wait for it
+	mountpoint, err := client.Mount("bar", "container")
+	require.NoError(t, err)
+	require.Equal(t, "/mnt/bar", mountpoint)
+	volumes, err := client.ListVolumes()
+	require.NoError(t, err)
+	require.Equal(t, 2, len(volumes))
+	fakeVolumeDriver.unblockMount("foo")
+	require.NoError(t, <-errC)
+	fakeVolumeDriver.requireStatusEquals("foo", fakeStatusMount)
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	runTest(t, testConcurrentAccess)
+}
+
+func testConcurrentAccess(t *testing.T, fakeVolumeDriver *fakeVolumeDriver, client VolumeDriverClient) {
+	numWorkers := 8
+	require.NoError(t, client.Create("shared", map[string]string{"key": "value"}))
+	var waitGroup sync.WaitGroup
+	errC := make(chan error, numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		waitGroup.Add(1)
+		go func(i int) {
+			defer waitGroup.Done()
+			errC <- runConcurrentAccessWorker(client, i)
+		}(i)
+	}
+	waitGroup.Wait()
+	close(errC)
+	for err := range errC {
+		require.NoError(t, err)
+	}
+	requireVolumesEqual(
+		t,
+		client,
+		&Volume{
+			Name: "shared",
+			Opts: map[string]string{"key": "value"},
+		},
+	)
+	fakeVolumeDriver.requireStatusEquals("shared", fakeStatusUnmount)
+}
+
+func runConcurrentAccessWorker(client VolumeDriverClient, i int) error {
+	name := fmt.Sprintf("volume%d", i)
+	id := fmt.Sprintf("container%d", i)
+	for j := 0; j < 20; j++ {
+		if err := client.Create(name, map[string]string{"key": "value"}); err != nil {
+			return err
+		}
+		if _, err := client.Mount(name, id); err != nil {
+			return err
+		}
+		if _, err := client.Mount("shared", id); err != nil {
+			return err
+		}
+		if _, err := client.ListVolumes(); err != nil {
+			return err
+		}
+		if _, err := client.Path("shared"); err != nil {
+			return err
+		}
+		if err := client.Unmount("shared", id); err != nil {
+			return err
+		}
+		if err := client.Unmount(name, id); err != nil {
+			return err
+		}
+		if err := client.Remove(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestErrors(t *testing.T) {
+	runTest(t, testErrors)
+}
+
+func testErrors(t *testing.T, fakeVolumeDriver *fakeVolumeDriver, client VolumeDriverClient) {
+	_, err := client.Get("foo")
+	requireVolumeError(t, err, "get", "foo", ErrVolumeNotFound)
+	_, err = client.GetVolume("foo")
+	requireVolumeError(t, err, "get", "foo", ErrVolumeNotFound)
+	_, err = client.Mount("foo", "container")
+	requireVolumeError(t, err, "mount", "foo", ErrVolumeNotFound)
+	require.NoError(t, client.Create("foo", map[string]string{"key": "value"}))
+	err = client.Create("foo", map[string]string{"key": "value"})
+	requireVolumeError(t, err, "create", "foo", ErrVolumeExists)
+	err = client.Unmount("foo", "container")
+	requireVolumeError(t, err, "unmount", "foo", ErrNotMounted)
+	_, err = client.Mount("foo", "container")
+	require.NoError(t, err)
+	err = client.Remove("foo")
+	requireVolumeError(t, err, "remove", "foo", ErrAlreadyMounted)
+	fakeVolumeDriver.failCreate("bar", errors.New("no space left"))
+	err = client.Create("bar", map[string]string{"key": "value"})
+	var driverError *DriverError
+	require.True(t, errors.As(err, &driverError))
+	require.Equal(t, "no space left", driverError.Err.Error())
+	require.Equal(t, "dockervolume: create bar: driver error: no space left", err.Error())
+	requireVolumesEqual(
+		t,
+		client,
+		&Volume{
+			Name:       "foo",
+			Opts:       map[string]string{"key": "value"},
+			Mountpoint: "/mnt/foo",
+			MountIds:   []string{"container"},
+		},
+	)
+}
+
+func TestUnmountError(t *testing.T) {
+	runTest(
+		t,
+		func(t *testing.T, fakeVolumeDriver *fakeVolumeDriver, client VolumeDriverClient) {
+			require.NoError(t, client.Create("foo", map[string]string{"key": "value"}))
+			_, err := client.Mount("foo", "container")
+			require.NoError(t, err)
+			fakeVolumeDriver.failUnmount("foo", errors.New("device busy"))
+			err = client.Unmount("foo", "container")
+			var driverError *DriverError
+			require.True(t, errors.As(err, &driverError))
+			// the volume is still mounted by the caller, so the unmount can be retried
+			requireVolumesEqual(
+				t,
+				client,
+				&Volume{
+					Name:       "foo",
+					Opts:       map[string]string{"key": "value"},
+					Mountpoint: "/mnt/foo",
+					MountIds:   []string{"container"},
+				},
+			)
+			fakeVolumeDriver.failUnmount("foo", nil)
+			require.NoError(t, client.Unmount("foo", "container"))
+			fakeVolumeDriver.requireStatusEquals("foo", fakeStatusUnmount)
+			requireVolumesEqual(
+				t,
+				client,
+				&Volume{
+					Name: "foo",
+					Opts: map[string]string{"key": "value"},
+				},
+			)
+		},
+	)
+}
+
+func TestErrorFromString(t *testing.T) {
+	for _, err := range []error{
+		newVolumeError("get", "foo", ErrVolumeNotFound),
+		newVolumeError("create", "foo bar", ErrVolumeExists),
+		newVolumeError("remove", "foo", ErrAlreadyMounted),
+		newVolumeError("unmount", "foo", ErrNotMounted),
+		newDriverError("mount", "foo", errors.New("mount: foo: permission denied")),
+		newVolumeError("resize", "foo", ErrUnimplemented),
+		ErrUnimplemented,
+		&DriverError{errors.New("list: permission denied")},
+	} {
+		require.Equal(t, err, errorFromString(err.Error()))
+	}
+	require.Equal(t, errors.New("foo"), errorFromString("foo"))
+}
+
+func requireVolumeError(t *testing.T, err error, op string, name string, expected error) {
+	require.True(t, errors.Is(err, expected), "%v is not %v", err, expected)
+	var volumeError *VolumeError
+	require.True(t, errors.As(err, &volumeError))
+	require.Equal(t, op, volumeError.Op)
+	require.Equal(t, name, volumeError.Name)
+}
+
+func TestCapabilities(t *testing.T) {
+	runTest(
+		t,
+		func(t *testing.T, fakeVolumeDriver *fakeVolumeDriver, client VolumeDriverClient) {
+			capabilities, err := client.Capabilities()
+			require.NoError(t, err)
+			require.Equal(t, &Capabilities{Scope: ScopeLocal}, capabilities)
+		},
+	)
+	runTestWithOptions(
+		t,
+		newFakeVolumeDriver(t),
+		APIServerOptions{Capabilities: &Capabilities{Scope: ScopeGlobal}},
+		func(t *testing.T, fakeVolumeDriver *fakeVolumeDriver, client VolumeDriverClient) {
+			capabilities, err := client.Capabilities()
+			require.NoError(t, err)
+			require.Equal(t, &Capabilities{Scope: ScopeGlobal}, capabilities)
+		},
+	)
+	_, err := newAPIServer(newFakeVolumeDriver(t), "test", APIServerOptions{Capabilities: &Capabilities{Scope: "foo"}})
+	require.Error(t, err)
+}
+
+func TestMountTimeout(t *testing.T) {
+	volumeDriver := newFakeVolumeDriver(t)
+	runTestWithOptions(
+		t,
+		volumeDriver,
+		APIServerOptions{MountTimeout: 10 * time.Millisecond},
+		func(t *testing.T, _ *fakeVolumeDriver, client VolumeDriverClient) {
+			require.NoError(t, client.Create("foo", map[string]string{"key": "value"}))
+			volumeDriver.blockMount("foo")
+			_, err := client.Mount("foo", "container")
+			require.Error(t, err)
+			requireVolumesEqual(
+				t,
+				client,
+				&Volume{
+					Name: "foo",
+					Opts: map[string]string{"key": "value"},
+				},
+			)
+			volumeDriver.unblockMount("foo")
+			mountpoint, err := client.Mount("foo", "container")
+			require.NoError(t, err)
+			require.Equal(t, "/mnt/foo", mountpoint)
+		},
+	)
+}
+
+func TestContextVolumeDriverAdapter(t *testing.T) {
+	volumeDriver := newFakeVolumeDriver(t)
+	contextVolumeDriver := newContextVolumeDriver(legacyVolumeDriver{volumeDriver})
+	_, ok := contextVolumeDriver.(*fakeVolumeDriver)
+	require.False(t, ok)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, context.Canceled, contextVolumeDriver.CreateContext(ctx, "foo", nil))
+	require.False(t, volumeDriver.hasStatus("foo"))
+	require.NoError(t, contextVolumeDriver.CreateContext(context.Background(), "foo", nil))
+	volumeDriver.requireStatusEquals("foo", fakeStatusCreate)
+	_, ok = newContextVolumeDriver(volumeDriver).(*fakeVolumeDriver)
+	require.True(t, ok)
+}
+
+func TestFileVolumeStoreRestart(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "dockervolume")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dirPath)
+	}()
+	volumeStore, err := NewFileVolumeStore(dirPath)
+	require.NoError(t, err)
+	volumeDriver := newFakeVolumeDriver(t)
+	expected := &Volume{
+		Name: "foo",
+		Opts: map[string]string{
+			"key": "value",
+		},
+		Mountpoint: "/mnt/foo",
+		MountIds:   []string{"container"},
+	}
+	runTestWithOptions(
+		t,
+		volumeDriver,
+		APIServerOptions{VolumeStore: volumeStore},
+		func(t *testing.T, fakeVolumeDriver *fakeVolumeDriver, client VolumeDriverClient) {
+			require.NoError(t, client.Create("foo", map[string]string{"key": "value"}))
+			require.NoError(t, client.Create("bar", nil))
+			_, err := client.Mount("foo", "container")
+			require.NoError(t, err)
+			require.NoError(t, client.Remove("bar"))
+		},
+	)
+	volumeStore, err = NewFileVolumeStore(dirPath)
+	require.NoError(t, err)
+	runTestWithOptions(
+		t,
+		volumeDriver,
+		APIServerOptions{VolumeStore: volumeStore},
+		func(t *testing.T, fakeVolumeDriver *fakeVolumeDriver, client VolumeDriverClient) {
+			requireVolumesEqual(t, client, expected)
+			require.NoError(t, client.Unmount("foo", "container"))
+			fakeVolumeDriver.requireStatusEquals("foo", fakeStatusUnmount)
+		},
+	)
+}
+
+func TestFileVolumeStoreInvalidFiles(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "dockervolume")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dirPath)
+	}()
+	volumeStore, err := NewFileVolumeStore(dirPath)
+	require.NoError(t, err)
+	require.NoError(t, volumeStore.Put(&Volume{Name: "foo"}))
+	// left behind by a crash during Put
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, fileVolumeStoreTempPrefix+"1234"), []byte("{"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "bar"+fileVolumeStoreExtension), []byte("{"), 0600))
+	volumeStore, err = NewFileVolumeStore(dirPath)
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dirPath, fileVolumeStoreTempPrefix+"1234"))
+	require.True(t, os.IsNotExist(err))
+	volumes, err := volumeStore.List()
+	require.NoError(t, err)
+	require.Equal(t, []*Volume{{Name: "foo"}}, volumes)
+}
+
+func TestVolumeStoreError(t *testing.T) {
+	volumeStore := &failingVolumeStore{NewMemoryVolumeStore(), nil}
+	runTestWithOptions(
+		t,
+		newFakeVolumeDriver(t),
+		APIServerOptions{VolumeStore: volumeStore},
+		func(t *testing.T, fakeVolumeDriver *fakeVolumeDriver, client VolumeDriverClient) {
+			require.NoError(t, client.Create("foo", map[string]string{"key": "value"}))
+			volumeStore.putErr = errors.New("disk full")
+			// the volumes of the volume driver are rolled back, and the volumes of the API are unchanged
+			require.Error(t, client.Create("bar", map[string]string{"key": "value"}))
+			fakeVolumeDriver.requireStatusEquals("bar", fakeStatusRemove)
+			_, err := client.Mount("foo", "container")
+			require.Error(t, err)
+			fakeVolumeDriver.requireStatusEquals("foo", fakeStatusUnmount)
+			requireVolumesEqual(
+				t,
+				client,
+				&Volume{
+					Name: "foo",
+					Opts: map[string]string{"key": "value"},
+				},
+			)
+		},
+	)
+}
+
+// failingVolumeStore is a VolumeStore whose Put fails with putErr if set.
+type failingVolumeStore struct {
+	VolumeStore
+	putErr error
+}
+
+func (f *failingVolumeStore) Put(volume *Volume) error {
+	if f.putErr != nil {
+		return f.putErr
+	}
+	return f.VolumeStore.Put(volume)
+}
+
+func TestVolumeDescription(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "dockervolume")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dirPath)
+	}()
+	runTest(
+		t,
+		func(t *testing.T, fakeVolumeDriver *fakeVolumeDriver, client VolumeDriverClient) {
+			start := time.Now().Unix()
+			require.NoError(t, client.Create("foo", nil))
+			volume, err := client.Get("foo")
+			require.NoError(t, err)
+			require.True(t, volume.CreatedAt.Seconds >= start)
+			require.True(t, volume.CreatedAt.Seconds <= time.Now().Unix())
+			require.Equal(t, map[string]string{"mountpoint": "", "num_mounts": "0"}, volume.Status)
+			require.Nil(t, volume.Usage)
+			_, err = client.Mount("foo", "container")
+			require.NoError(t, err)
+			volume, err = client.GetVolume("foo")
+			require.NoError(t, err)
+			require.Equal(t, map[string]string{"mountpoint": "/mnt/foo", "num_mounts": "1"}, volume.Status)
+			// listing does not call the volume driver for every volume
+			volumes, err := client.List()
+			require.NoError(t, err)
+			require.Len(t, volumes, 1)
+			require.Nil(t, volumes[0].Status)
+			volumes, err = client.ListVolumes()
+			require.NoError(t, err)
+			require.Len(t, volumes, 1)
+			require.Nil(t, volumes[0].Status)
+		},
+	)
+	apiServer, err := newAPIServer(legacyVolumeDriver{newFakeVolumeDriver(t)}, "test", APIServerOptions{})
+	require.NoError(t, err)
+	volume := apiServer.describeVolume(context.Background(), &Volume{Name: "foo", Mountpoint: dirPath})
+	require.Nil(t, volume.Status)
+	if runtime.GOOS == "linux" {
+		require.NotNil(t, volume.Usage)
+		require.True(t, volume.Usage.TotalBytes > 0)
+		require.True(t, volume.Usage.UsedBytes <= volume.Usage.TotalBytes)
+	}
+}
+
+func TestStatusTimeout(t *testing.T) {
+	volumeDriver := &blockingStatusVolumeDriver{newFakeVolumeDriver(t), make(chan struct{})}
+	defer close(volumeDriver.release)
+	runTestWithVolumeDriver(
+		t,
+		volumeDriver,
+		APIServerOptions{
+			StatusTimeout: 10 * time.Millisecond,
+		},
+		func(t *testing.T, client VolumeDriverClient) {
+			require.NoError(t, client.Create("foo", nil))
+			volume, err := client.Get("foo")
+			require.NoError(t, err)
+			require.Equal(t, map[string]string{statusErrorKey: context.DeadlineExceeded.Error()}, volume.Status)
+		},
+	)
+}
+
+// blockingStatusVolumeDriver is a fakeVolumeDriver whose Status blocks until release is closed.
+type blockingStatusVolumeDriver struct {
+	*fakeVolumeDriver
+	release chan struct{}
+}
+
+func (v *blockingStatusVolumeDriver) Status(name string, opts pkgmap.StringStringMap, mountpoint string) (map[string]string, error) {
+	<-v.release
+	return v.fakeVolumeDriver.Status(name, opts, mountpoint)
+}
+
+func TestWatch(t *testing.T) {
+	apiServer, err := newAPIServer(newFakeVolumeDriver(t), "test", APIServerOptions{})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	watchServer := &fakeWatchServer{
+		ctx:    ctx,
+		events: make(chan *Event, 16),
+	}
+	errC := make(chan error, 1)
+	go func() {
+		errC <- apiServer.Watch(google_protobuf.EmptyInstance, watchServer)
+	}()
+	for apiServer.eventBroadcaster.numSubscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	volumeDriver := apiServer.volumeDriver.(*fakeVolumeDriver)
+	volumeDriver.failCreate("bar", errors.New("no space left"))
+	require.NoError(t, apiServer.create(context.Background(), "foo", map[string]string{"key": "value"}))
+	require.Error(t, apiServer.create(context.Background(), "bar", nil))
+	_, err = apiServer.mount(context.Background(), "foo", "a")
+	require.NoError(t, err)
+	_, err = apiServer.mount(context.Background(), "foo", "b")
+	require.NoError(t, err)
+	require.NoError(t, apiServer.unmount(context.Background(), "foo", "a"))
+	require.NoError(t, apiServer.unmount(context.Background(), "foo", "b"))
+	require.NoError(t, apiServer.remove(context.Background(), "foo"))
+	// not a change, so no event
+	require.Error(t, apiServer.remove(context.Background(), "foo"))
+	for _, expected := range []struct {
+		eventType  EventType
+		name       string
+		id         string
+		mountpoint string
+		failed     bool
+	}{
+		{EventType_EVENT_TYPE_CREATE, "foo", "", "", false},
+		{EventType_EVENT_TYPE_CREATE, "bar", "", "", true},
+		{EventType_EVENT_TYPE_MOUNT, "foo", "a", "/mnt/foo", false},
+		{EventType_EVENT_TYPE_MOUNT, "foo", "b", "/mnt/foo", false},
+		{EventType_EVENT_TYPE_UNMOUNT, "foo", "a", "/mnt/foo", false},
+		{EventType_EVENT_TYPE_UNMOUNT, "foo", "b", "", false},
+		{EventType_EVENT_TYPE_REMOVE, "foo", "", "", false},
+	} {
+		event := <-watchServer.events
+		require.Equal(t, expected.eventType, event.Type)
+		require.Equal(t, expected.name, event.Volume.Name)
+		require.Equal(t, expected.id, event.Id)
+		require.Equal(t, expected.mountpoint, event.Volume.Mountpoint)
+		require.Equal(t, expected.failed, event.Err != "")
+		require.NotNil(t, event.Timestamp)
+	}
+	cancel()
+	require.Equal(t, context.Canceled, <-errC)
+	require.Equal(t, 0, apiServer.eventBroadcaster.numSubscribers())
+}
+
+func TestEventBroadcasterDropsSlowSubscriber(t *testing.T) {
+	eventBroadcaster := newEventBroadcaster(1)
+	slow, unsubscribeSlow := eventBroadcaster.subscribe()
+	fast, unsubscribeFast := eventBroadcaster.subscribe()
+	defer unsubscribeFast()
+	eventBroadcaster.publish(&Event{Type: EventType_EVENT_TYPE_CREATE})
+	require.Equal(t, EventType_EVENT_TYPE_CREATE, (<-fast).Type)
+	eventBroadcaster.publish(&Event{Type: EventType_EVENT_TYPE_REMOVE})
+	require.Equal(t, EventType_EVENT_TYPE_REMOVE, (<-fast).Type)
+	require.Equal(t, EventType_EVENT_TYPE_CREATE, (<-slow).Type)
+	_, ok := <-slow
+	require.False(t, ok)
+	require.Equal(t, 1, eventBroadcaster.numSubscribers())
+	// unsubscribing a dropped subscriber is a no-op
+	unsubscribeSlow()
+}
+
+func TestListVolumesFilters(t *testing.T) {
+	runTest(
+		t,
+		func(t *testing.T, fakeVolumeDriver *fakeVolumeDriver, client VolumeDriverClient) {
+			require.NoError(t, client.Create("team-foo-1", map[string]string{"team": "foo", "env": "prod"}))
+			require.NoError(t, client.Create("team-foo-2", map[string]string{"team": "foo"}))
+			require.NoError(t, client.Create("team-bar-1", map[string]string{"team": "bar", "env": "dev"}))
+			require.NoError(t, client.Create("other", nil))
+			_, err := client.Mount("team-foo-2", "container")
+			require.NoError(t, err)
+			for _, testCase := range []struct {
+				request  *ListVolumesRequest
+				expected []string
+			}{
+				{&ListVolumesRequest{}, []string{"other", "team-bar-1", "team-foo-1", "team-foo-2"}},
+				{&ListVolumesRequest{NamePrefix: "team-"}, []string{"team-bar-1", "team-foo-1", "team-foo-2"}},
+				{&ListVolumesRequest{Selector: []string{"team=foo"}}, []string{"team-foo-1", "team-foo-2"}},
+				{&ListVolumesRequest{Selector: []string{"team=foo", "env"}}, []string{"team-foo-1"}},
+				{&ListVolumesRequest{Selector: []string{"env"}}, []string{"team-bar-1", "team-foo-1"}},
+				{&ListVolumesRequest{Selector: []string{"team="}}, nil},
+				{&ListVolumesRequest{MountState: MountState_MOUNT_STATE_MOUNTED}, []string{"team-foo-2"}},
+				{&ListVolumesRequest{MountState: MountState_MOUNT_STATE_UNMOUNTED, NamePrefix: "team-foo"}, []string{"team-foo-1"}},
+			} {
+				response, err := client.ListVolumesPage(testCase.request)
+				require.NoError(t, err)
+				require.Equal(t, testCase.expected, volumeNames(response.Volume))
+				require.Equal(t, "", response.NextPageToken)
+			}
+			for _, selector := range [][]string{
+				{"=foo"},
+				{"team=foo", "team=bar"},
+				{"team=foo", "team"},
+			} {
+				_, err = client.ListVolumesPage(&ListVolumesRequest{Selector: selector})
+				require.Equal(t, codes.InvalidArgument, grpc.Code(err), "%v", selector)
+			}
+			_, err = client.ListVolumesPage(&ListVolumesRequest{PageToken: "%"})
+			require.Error(t, err)
+		},
+	)
+}
+
+func TestListVolumesPagination(t *testing.T) {
+	runTest(
+		t,
+		func(t *testing.T, fakeVolumeDriver *fakeVolumeDriver, client VolumeDriverClient) {
+			for _, name := range []string{"e", "a", "d", "b", "c"} {
+				require.NoError(t, client.Create(name, nil))
+			}
+			response, err := client.ListVolumesPage(&ListVolumesRequest{PageSize: 2})
+			require.NoError(t, err)
+			require.Equal(t, []string{"a", "b"}, volumeNames(response.Volume))
+			// volumes created and removed between pages do not shift the next pages
+			require.NoError(t, client.Remove("a"))
+			require.NoError(t, client.Create("aa", nil))
+			response, err = client.ListVolumesPage(&ListVolumesRequest{PageSize: 2, PageToken: response.NextPageToken})
+			require.NoError(t, err)
+			require.Equal(t, []string{"c", "d"}, volumeNames(response.Volume))
+			require.NotEqual(t, "", response.NextPageToken)
+			response, err = client.ListVolumesPage(&ListVolumesRequest{PageSize: 2, PageToken: response.NextPageToken})
+			require.NoError(t, err)
+			require.Equal(t, []string{"e"}, volumeNames(response.Volume))
+			require.Equal(t, "", response.NextPageToken)
+			response, err = client.ListVolumesPage(&ListVolumesRequest{PageSize: 4})
+			require.NoError(t, err)
+			require.Equal(t, []string{"aa", "b", "c", "d"}, volumeNames(response.Volume))
+			require.NotEqual(t, "", response.NextPageToken)
+		},
+	)
+}
+
+func volumeNames(volumes []*Volume) []string {
+	var names []string
+	for _, volume := range volumes {
+		names = append(names, volume.Name)
+	}
+	return names
+}
+
+func TestCleanup(t *testing.T) {
+	dockerClient := newFakeDockerClient("test")
+	runTestWithOptions(
+		t,
+		newFakeVolumeDriver(t),
+		APIServerOptions{DockerClient: dockerClient},
+		func(t *testing.T, fakeVolumeDriver *fakeVolumeDriver, client VolumeDriverClient) {
+			dockerClient.setRemove(client.Remove)
+			for _, name := range []string{"used", "unused", "mounted", "other-team"} {
+				team := "foo"
+				if name == "other-team" {
+					team = "bar"
+				}
+				require.NoError(t, client.Create(name, map[string]string{"team": team}))
+				dockerClient.addVolume(name, "test")
+			}
+			require.NoError(t, client.Create("unknown-to-docker", map[string]string{"team": "foo"}))
+			dockerClient.addVolume("other-driver", "local")
+			dockerClient.addContainer("container", "used")
+			// mounted by a caller that is gone
+			_, err := client.Mount("mounted", "gone")
+			require.NoError(t, err)
+
+			volumes, err := client.CleanupVolumes(&CleanupRequest{DryRun: true, OnlyUnused: true})
+			require.NoError(t, err)
+			require.Equal(t, []string{"mounted", "other-team", "unused"}, volumeNames(volumes))
+			volumes, err = client.ListVolumes()
+			require.NoError(t, err)
+			require.Len(t, volumes, 5)
+
+			_, err = client.CleanupVolumes(&CleanupRequest{Selector: []string{"team=foo"}, OnlyUnused: true})
+			requireVolumeError(t, err, "remove", "mounted", ErrAlreadyMounted)
+			_, err = client.Get("unused")
+			requireVolumeError(t, err, "get", "unused", ErrVolumeNotFound)
+
+			volumes, err = client.CleanupVolumes(&CleanupRequest{Selector: []string{"team=foo"}, OnlyUnused: true, Force: true})
+			require.NoError(t, err)
+			require.Equal(t, []string{"mounted"}, volumeNames(volumes))
+			fakeVolumeDriver.requireStatusEquals("mounted", fakeStatusRemove)
+
+			// docker refuses to remove a volume used by a container
+			_, err = client.CleanupVolumes(&CleanupRequest{NamePrefix: "used"})
+			require.Error(t, err)
+			volumes, err = client.ListVolumes()
+			require.NoError(t, err)
+			require.Equal(t, []string{"other-team", "unknown-to-docker", "used"}, volumeNames(volumes))
+			require.Equal(t, []string{"other-driver", "other-team", "used"}, dockerClient.volumeNames())
+		},
+	)
+}
+
+func TestReconcileOnStart(t *testing.T) {
+	volumeStore := NewMemoryVolumeStore()
+	require.NoError(
+		t,
+		volumeStore.Put(
+			&Volume{
+				Name:       "stale",
+				Mountpoint: "/dockervolume/does/not/exist",
+				MountIds:   []string{"container"},
+				CreatedAt:  timeToTimestamp(time.Now()),
+			},
+		),
+	)
+	dockerClient := newFakeDockerClient("test")
+	dockerClient.addVolume("stale", "test")
+	dockerClient.addVolume("adopted", "test")
+	runTestWithVolumeDriver(
+		t,
+		legacyVolumeDriver{newFakeVolumeDriver(t)},
+		APIServerOptions{
+			VolumeStore:      volumeStore,
+			DockerClient:     dockerClient,
+			ReconcileOnStart: &ReconcileRequest{Repair: true},
+		},
+		func(t *testing.T, client VolumeDriverClient) {
+			volume, err := client.Get("stale")
+			require.NoError(t, err)
+			require.Equal(t, "", volume.Mountpoint)
+			require.Empty(t, volume.MountIds)
+			_, err = client.Get("adopted")
+			require.NoError(t, err)
+			response, err := client.Reconcile(false)
+			require.NoError(t, err)
+			require.Equal(t, &ReconcileResponse{}, response)
+		},
+	)
+}
+
+func TestReconcile(t *testing.T) {
+	volumeDriver := newFakeVolumeDriver(t)
+	apiServer, err := newAPIServer(volumeDriver, "test", APIServerOptions{})
+	require.NoError(t, err)
+	for _, name := range []string{"mounted", "lost", "unknown"} {
+		require.NoError(t, apiServer.create(context.Background(), name, map[string]string{"key": "value"}))
+	}
+	// the fake driver mounts on /mnt/mounted, which does not exist
+	_, err = apiServer.mount(context.Background(), "mounted", "container")
+	require.NoError(t, err)
+	volumeDriver.loseVolume("lost")
+	require.NoError(t, volumeDriver.Create("adopted", nil))
+	require.NoError(t, volumeDriver.Create("orphan", nil))
+	dockerVolumeNames := []string{"mounted", "lost", "adopted", "gone"}
+
+	response, err := apiServer.reconcile(dockerVolumeNames, false)
+	require.NoError(t, err)
+	expected := &ReconcileResponse{
+		MissingInDocker:  []string{"unknown"},
+		MissingInApi:     []string{"adopted", "gone"},
+		MissingInDriver:  []string{"lost"},
+		DriverOrphans:    []string{"orphan"},
+		StaleMountpoints: []string{"mounted"},
+	}
+	require.Equal(t, expected, response)
+	require.Len(t, apiServer.list(), 3)
+
+	response, err = apiServer.reconcile(dockerVolumeNames, true)
+	require.NoError(t, err)
+	expected.Repaired = []string{"adopted", "lost", "mounted"}
+	require.Equal(t, expected, response)
+	volume, ok := apiServer.getVolume("mounted")
+	require.True(t, ok)
+	require.Equal(t, "", volume.Mountpoint)
+	require.Empty(t, volume.MountIds)
+	_, ok = apiServer.getVolume("lost")
+	require.False(t, ok)
+	volume, ok = apiServer.getVolume("adopted")
+	require.True(t, ok)
+	require.Empty(t, volume.Opts)
+	require.Equal(t, "", volume.Mountpoint)
+
+	response, err = apiServer.reconcile(dockerVolumeNames, true)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		&ReconcileResponse{
+			MissingInDocker: []string{"unknown"},
+			MissingInApi:    []string{"gone", "lost"},
+			DriverOrphans:   []string{"orphan"},
+		},
+		response,
+	)
+}
+
+func TestPluginVolumeJSON(t *testing.T) {
+	apiServer, err := newAPIServer(newFakeVolumeDriver(t), "test", APIServerOptions{})
+	require.NoError(t, err)
+	start := time.Now().Add(-time.Second)
+	require.NoError(t, apiServer.create(context.Background(), "foo", map[string]string{"key": "value"}))
+	volumeErrResponse, err := apiServer.Get(context.Background(), &NameRequest{Name: "foo"})
+	require.NoError(t, err)
+	data, err := json.Marshal(volumeErrResponse)
+	require.NoError(t, err)
+	// the response as docker decodes it
+	dockerResponse := &struct {
+		Volume struct {
+			Name       string
+			Mountpoint string
+			CreatedAt  string
+			Status     map[string]interface{}
+		}
+		Err string
+	}{}
+	require.NoError(t, json.Unmarshal(data, dockerResponse))
+	require.Equal(t, "foo", dockerResponse.Volume.Name)
+	require.Equal(t, "", dockerResponse.Err)
+	createdAt, err := time.Parse(time.RFC3339, dockerResponse.Volume.CreatedAt)
+	require.NoError(t, err)
+	require.True(t, createdAt.After(start))
+	require.Equal(t, map[string]interface{}{"mountpoint": "", "num_mounts": "0"}, dockerResponse.Volume.Status)
+}
+
+func TestReconcileDriverError(t *testing.T) {
+	runTestWithOptions(
+		t,
+		newFakeVolumeDriver(t),
+		APIServerOptions{DockerClient: newFakeDockerClient("test")},
+		func(t *testing.T, fakeVolumeDriver *fakeVolumeDriver, client VolumeDriverClient) {
+			fakeVolumeDriver.failList(errors.New("permission denied"))
+			_, err := client.Reconcile(false)
+			var driverError *DriverError
+			require.True(t, errors.As(err, &driverError), "%v is not a DriverError", err)
+			require.Equal(t, "permission denied", driverError.Err.Error())
+		},
+	)
+}
+
+func TestTransportError(t *testing.T) {
+	transportErr := grpc.Errorf(codes.Unavailable, "transport is closing")
+	mountpoint, err := callNameToMountpointErr(
+		"foo",
+		func(context.Context, *NameRequest, ...grpc.CallOption) (*MountpointErrResponse, error) {
+			return nil, transportErr
+		},
+	)
+	require.Equal(t, transportErr, err)
+	require.Equal(t, "", mountpoint)
+}
+
+func TestExtensions(t *testing.T) {
+	runTest(
+		t,
+		func(t *testing.T, fakeVolumeDriver *fakeVolumeDriver, client VolumeDriverClient) {
+			require.NoError(t, client.Create("foo", nil))
+			require.NoError(t, client.Create("bar", nil))
+			names, err := client.ListDriverVolumes()
+			require.NoError(t, err)
+			require.Equal(t, []string{"bar", "foo"}, names)
+			_, err = client.Mount("foo", "container")
+			require.NoError(t, err)
+			status, err := client.GetVolumeStatus("foo")
+			require.NoError(t, err)
+			require.Equal(t, map[string]string{"mountpoint": "/mnt/foo", "num_mounts": "1"}, status)
+			require.NoError(t, client.SnapshotVolume("foo", "first"))
+			require.NoError(t, client.SnapshotVolume("foo", "second"))
+			fakeVolumeDriver.requireSnapshotsEqual("foo", "first", "second")
+			require.NoError(t, client.ResizeVolume("foo", 1024))
+			fakeVolumeDriver.requireSizeBytesEquals("foo", 1024)
+			err = client.ResizeVolume("foo", 0)
+			var driverError *DriverError
+			require.True(t, errors.As(err, &driverError))
+			requireVolumeError(t, client.SnapshotVolume("baz", "first"), "snapshot", "baz", ErrVolumeNotFound)
+			_, err = client.GetVolumeStatus("baz")
+			requireVolumeError(t, err, "status", "baz", ErrVolumeNotFound)
+		},
+	)
+	runTestWithVolumeDriver(
+		t,
+		legacyVolumeDriver{newFakeVolumeDriver(t)},
+		APIServerOptions{},
+		func(t *testing.T, client VolumeDriverClient) {
+			require.NoError(t, client.Create("foo", nil))
+			_, err := client.ListDriverVolumes()
+			require.True(t, errors.Is(err, ErrUnimplemented))
+			_, err = client.GetVolumeStatus("foo")
+			requireVolumeError(t, err, "status", "foo", ErrUnimplemented)
+			requireVolumeError(t, client.SnapshotVolume("foo", "first"), "snapshot", "foo", ErrUnimplemented)
+			requireVolumeError(t, client.ResizeVolume("foo", 1024), "resize", "foo", ErrUnimplemented)
+		},
+	)
+}
+
+func TestPluginClient(t *testing.T) {
+	runTestWithPluginClient(t, newFakeVolumeDriver(t), testPluginClient)
+	dirPath, err := ioutil.TempDir("", "dockervolume")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dirPath) }()
+	_, err = NewPluginClient("localhost:1234", PluginClientOptions{})
+	require.Error(t, err)
+	client, err := NewPluginClient("unix://"+filepath.Join(dirPath, "missing.sock"), PluginClientOptions{})
+	require.NoError(t, err)
+	_, err = client.Activate()
+	require.Error(t, err)
+}
+
+func TestPluginClientWithoutCapabilities(t *testing.T) {
+	// plugins that predate capabilities only have the other endpoints
+	serveMux := http.NewServeMux()
+	serveMux.HandleFunc("/Plugin.Activate", func(responseWriter http.ResponseWriter, _ *http.Request) {
+		_, _ = responseWriter.Write([]byte(`{"Implements":["VolumeDriver"]}`))
+	})
+	server := httptest.NewServer(serveMux)
+	defer server.Close()
+	client, err := NewPluginClient("tcp://"+server.Listener.Addr().String(), PluginClientOptions{})
+	require.NoError(t, err)
+	implements, err := client.Activate()
+	require.NoError(t, err)
+	require.Equal(t, []string{"VolumeDriver"}, implements)
+	capabilities, err := client.Capabilities()
+	require.NoError(t, err)
+	require.Equal(t, ScopeLocal, capabilities.Scope)
+	_, err = client.List()
+	require.Error(t, err)
+}
+
+func testPluginClient(t *testing.T, client PluginClient) {
+	implements, err := client.Activate()
+	require.NoError(t, err)
+	require.Equal(t, []string{"VolumeDriver"}, implements)
+	capabilities, err := client.Capabilities()
+	require.NoError(t, err)
+	require.Equal(t, ScopeLocal, capabilities.Scope)
+	start := time.Now().Add(-time.Second).Unix()
+	require.NoError(t, client.Create("foo", map[string]string{"key": "value"}))
+	requireVolumeError(t, client.Create("foo", nil), "create", "foo", ErrVolumeExists)
+	mountpoint, err := client.Mount("foo", "container")
+	require.NoError(t, err)
+	require.Equal(t, "/mnt/foo", mountpoint)
+	mountpoint, err = client.Path("foo")
+	require.NoError(t, err)
+	require.Equal(t, "/mnt/foo", mountpoint)
+	volume, err := client.Get("foo")
+	require.NoError(t, err)
+	require.Equal(t, "foo", volume.Name)
+	require.Equal(t, "/mnt/foo", volume.Mountpoint)
+	require.NotNil(t, volume.CreatedAt)
+	require.True(t, volume.CreatedAt.Seconds >= start)
+	require.Equal(t, map[string]string{"mountpoint": "/mnt/foo", "num_mounts": "1"}, volume.Status)
+	volumes, err := client.List()
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo"}, volumeNames(volumes))
+	requireVolumeError(t, client.Remove("foo"), "remove", "foo", ErrAlreadyMounted)
+	require.NoError(t, client.Unmount("foo", "container"))
+	require.NoError(t, client.Remove("foo"))
+	_, err = client.Get("foo")
+	requireVolumeError(t, err, "get", "foo", ErrVolumeNotFound)
+}
+
+func TestPluginVolume(t *testing.T) {
+	pluginVolume := &pluginVolume{}
+	require.NoError(
+		t,
+		json.Unmarshal(
+			[]byte(`{"Name":"foo","Mountpoint":"/mnt/foo","CreatedAt":"2016-01-02T03:04:05Z","Status":{"key":"value","num_mounts":1}}`),
+			pluginVolume,
+		),
+	)
+	require.Equal(
+		t,
+		&Volume{
+			Name:       "foo",
+			Mountpoint: "/mnt/foo",
+			CreatedAt:  timeToTimestamp(time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)),
+			Status:     map[string]string{"key": "value", "num_mounts": "1"},
+		},
+		pluginVolume.toVolume(),
+	)
 }
 
 func requireVolumesEqual(t *testing.T, client VolumeDriverClient, expected ...*Volume) {
 	volumes, err := client.ListVolumes()
 	require.NoError(t, err)
+	requireVolumeListEqual(t, volumes, expected...)
+	volumes, err = client.List()
+	require.NoError(t, err)
+	requireVolumeListEqual(t, volumes, toPluginVolumeFields(expected)...)
+	for _, expected := range expected {
+		volume, err := client.GetVolume(expected.Name)
+		require.NoError(t, err)
+		requireVolumeEqual(t, expected, volume)
+		volume, err = client.Get(expected.Name)
+		require.NoError(t, err)
+		requireVolumeEqual(t, toPluginVolumeFields([]*Volume{expected})[0], volume)
+	}
+}
+
+func requireVolumeListEqual(t *testing.T, volumes []*Volume, expected ...*Volume) {
 	require.Equal(t, len(expected), len(volumes))
-	nameToExpected := make(map[string]*Volume)
 	nameToActual := make(map[string]*Volume)
-	for _, volume := range expected {
-		nameToExpected[volume.Name] = volume
-	}
 	for _, volume := range volumes {
 		nameToActual[volume.Name] = volume
 	}
-	require.Equal(t, len(nameToExpected), len(nameToActual))
-	for name, expected := range nameToExpected {
-		actual, ok := nameToActual[name]
+	require.Equal(t, len(expected), len(nameToActual))
+	for _, expected := range expected {
+		actual, ok := nameToActual[expected.Name]
 		require.True(t, ok)
-		require.Equal(t, expected, actual)
-		volume, err := client.GetVolume(name)
-		require.NoError(t, err)
-		require.Equal(t, expected, volume)
+		requireVolumeEqual(t, expected, actual)
+	}
+}
+
+// toPluginVolumeFields returns copies of the volumes with only the fields
+// the docker volume plugin API has.
+func toPluginVolumeFields(volumes []*Volume) []*Volume {
+	pluginVolumes := make([]*Volume, len(volumes))
+	for i, volume := range volumes {
+		pluginVolumes[i] = &Volume{
+			Name:       volume.Name,
+			Mountpoint: volume.Mountpoint,
+		}
 	}
+	return pluginVolumes
+}
+
+// requireVolumeEqual ignores the fields that depend on when and where the test runs.
+func requireVolumeEqual(t *testing.T, expected *Volume, actual *Volume) {
+	require.NotNil(t, actual.CreatedAt)
+	actualCopy := *actual
+	actual = &actualCopy
+	actual.CreatedAt = nil
+	actual.Status = nil
+	actual.Usage = nil
+	require.Equal(t, expected, actual)
 }
 
 func runTest(
 	t *testing.T,
 	testFunc func(*testing.T, *fakeVolumeDriver, VolumeDriverClient),
 ) {
-	fakeVolumeDriver := newFakeVolumeDriver(t)
+	runTestWithOptions(t, newFakeVolumeDriver(t), APIServerOptions{}, testFunc)
+}
+
+func runTestWithOptions(
+	t *testing.T,
+	fakeVolumeDriver *fakeVolumeDriver,
+	opts APIServerOptions,
+	testFunc func(*testing.T, *fakeVolumeDriver, VolumeDriverClient),
+) {
+	runTestWithVolumeDriver(
+		t,
+		fakeVolumeDriver,
+		opts,
+		func(t *testing.T, client VolumeDriverClient) {
+			testFunc(t, fakeVolumeDriver, client)
+		},
+	)
+}
+
+func runTestWithVolumeDriver(
+	t *testing.T,
+	volumeDriver VolumeDriver,
+	opts APIServerOptions,
+	testFunc func(*testing.T, VolumeDriverClient),
+) {
+	apiServer, err := newAPIServer(volumeDriver, "test", opts)
+	require.NoError(t, err)
+	prototest.RunT(
+		t,
+		1,
+		func(addressToServer map[string]*grpc.Server) {
+			for _, server := range addressToServer {
+				RegisterAPIServer(server, apiServer)
+			}
+		},
+		func(t *testing.T, addressToClientConn map[string]*grpc.ClientConn) {
+			var clientConn *grpc.ClientConn
+			for _, cc := range addressToClientConn {
+				clientConn = cc
+				break
+			}
+			testFunc(t, NewVolumeDriverClient(NewAPIClient(clientConn)))
+		},
+	)
+}
+
+// runTestWithPluginClient serves the APIServer with the HTTP handler of the
+// docker volume plugin protocol, as a plugin does.
+func runTestWithPluginClient(
+	t *testing.T,
+	volumeDriver VolumeDriver,
+	testFunc func(*testing.T, PluginClient),
+) {
+	apiServer, err := newAPIServer(volumeDriver, "test", APIServerOptions{})
+	require.NoError(t, err)
 	prototest.RunT(
 		t,
 		1,
 		func(addressToServer map[string]*grpc.Server) {
 			for _, server := range addressToServer {
-				RegisterAPIServer(server, newAPIServer(fakeVolumeDriver, "test"))
+				RegisterAPIServer(server, apiServer)
 			}
 		},
 		func(t *testing.T, addressToClientConn map[string]*grpc.ClientConn) {
@@ -118,15 +1220,56 @@ func runTest(
 				clientConn = cc
 				break
 			}
-			testFunc(t, fakeVolumeDriver, NewVolumeDriverClient(NewAPIClient(clientConn)))
+			mux := gatewayruntime.NewServeMux()
+			require.NoError(t, RegisterAPIHandler(context.Background(), mux, clientConn))
+			// Plugin.Activate is served by go.pedge.io/dockerplugin in a plugin
+			serveMux := http.NewServeMux()
+			serveMux.HandleFunc(
+				"/Plugin.Activate",
+				func(w http.ResponseWriter, _ *http.Request) {
+					w.Header().Set("Content-Type", pluginContentType)
+					_ = json.NewEncoder(w).Encode(&pluginActivateResponse{Implements: []string{"VolumeDriver"}})
+				},
+			)
+			serveMux.Handle("/", mux)
+			dirPath, err := ioutil.TempDir("", "dockervolume")
+			require.NoError(t, err)
+			defer func() { _ = os.RemoveAll(dirPath) }()
+			socketPath := filepath.Join(dirPath, "test.sock")
+			listener, err := net.Listen("unix", socketPath)
+			require.NoError(t, err)
+			defer func() { _ = listener.Close() }()
+			go func() { _ = http.Serve(listener, serveMux) }()
+			client, err := NewPluginClient("unix://"+socketPath, PluginClientOptions{})
+			require.NoError(t, err)
+			testFunc(t, client)
 		},
 	)
 }
 
+// fakeVolumeDriver is the VolumeDriver of the tests of this package. They can
+// not use dockervolumetest.FakeVolumeDriver, as dockervolumetest imports this
+// package, and they need what that fake does not have: blocked mounts, the
+// statuses, snapshots and sizes of the optional VolumeDriver interfaces, and
+// volumes lost behind the back of the API. It is safe for concurrent use.
 type fakeVolumeDriver struct {
 	t                *testing.T
 	nameToFakeVolume map[string]*Volume
 	nameToFakeStatus map[string]fakeStatus
+	nameToNumMounts  map[string]int
+	nameToMountBlock map[string]*fakeMountBlock
+	nameToCreateErr  map[string]error
+	nameToUnmountErr map[string]error
+	nameToSnapshots  map[string][]string
+	nameToSizeBytes  map[string]uint64
+	listErr          error
+	lock             *sync.Mutex
+}
+
+type fakeMountBlock struct {
+	blocked     chan struct{}
+	blockedOnce *sync.Once
+	release     chan struct{}
 }
 
 func newFakeVolumeDriver(t *testing.T) *fakeVolumeDriver {
@@ -134,10 +1277,23 @@ func newFakeVolumeDriver(t *testing.T) *fakeVolumeDriver {
 		t,
 		make(map[string]*Volume),
 		make(map[string]fakeStatus),
+		make(map[string]int),
+		make(map[string]*fakeMountBlock),
+		make(map[string]error),
+		make(map[string]error),
+		make(map[string][]string),
+		make(map[string]uint64),
+		nil,
+		&sync.Mutex{},
 	}
 }
 
 func (v *fakeVolumeDriver) Create(name string, opts pkgmap.StringStringMap) error {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if err, ok := v.nameToCreateErr[name]; ok {
+		return err
+	}
 	v.nameToFakeVolume[name] = &Volume{
 		Name:       name,
 		Opts:       opts,
@@ -148,25 +1304,311 @@ func (v *fakeVolumeDriver) Create(name string, opts pkgmap.StringStringMap) erro
 }
 
 func (v *fakeVolumeDriver) Remove(name string, opts pkgmap.StringStringMap, mountpoint string) error {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	delete(v.nameToFakeVolume, name)
 	v.nameToFakeStatus[name] = fakeStatusRemove
 	return nil
 }
 
 func (v *fakeVolumeDriver) Mount(name string, opts pkgmap.StringStringMap) (string, error) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
 	mountpoint := fmt.Sprintf("/mnt/%s", name)
 	v.nameToFakeVolume[name].Mountpoint = mountpoint
 	v.nameToFakeStatus[name] = fakeStatusMount
+	v.nameToNumMounts[name]++
 	return mountpoint, nil
 }
 
 func (v *fakeVolumeDriver) Unmount(name string, opts pkgmap.StringStringMap, mountpoint string) error {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if err, ok := v.nameToUnmountErr[name]; ok {
+		return err
+	}
 	v.nameToFakeVolume[name].Mountpoint = mountpoint
 	v.nameToFakeStatus[name] = fakeStatusUnmount
 	return nil
 }
 
+func (v *fakeVolumeDriver) CreateContext(_ context.Context, name string, opts pkgmap.StringStringMap) error {
+	return v.Create(name, opts)
+}
+
+func (v *fakeVolumeDriver) RemoveContext(_ context.Context, name string, opts pkgmap.StringStringMap, mountpoint string) error {
+	return v.Remove(name, opts, mountpoint)
+}
+
+// MountContext blocks until the block is released or ctx is done if blockMount was called for name.
+func (v *fakeVolumeDriver) MountContext(ctx context.Context, name string, opts pkgmap.StringStringMap) (string, error) {
+	v.lock.Lock()
+	fakeMountBlock, ok := v.nameToMountBlock[name]
+	v.lock.Unlock()
+	if ok {
+		fakeMountBlock.blockedOnce.Do(func() { close(fakeMountBlock.blocked) })
+		select {
+		case <-fakeMountBlock.release:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	return v.Mount(name, opts)
+}
+
+func (v *fakeVolumeDriver) UnmountContext(_ context.Context, name string, opts pkgmap.StringStringMap, mountpoint string) error {
+	return v.Unmount(name, opts, mountpoint)
+}
+
+func (v *fakeVolumeDriver) ListVolumeNames() ([]string, error) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if v.listErr != nil {
+		return nil, v.listErr
+	}
+	var names []string
+	for name := range v.nameToFakeVolume {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (v *fakeVolumeDriver) Status(name string, opts pkgmap.StringStringMap, mountpoint string) (map[string]string, error) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return map[string]string{
+		"mountpoint": mountpoint,
+		"num_mounts": strconv.Itoa(v.nameToNumMounts[name]),
+	}, nil
+}
+
+func (v *fakeVolumeDriver) Snapshot(name string, opts pkgmap.StringStringMap, mountpoint string, snapshotName string) error {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.nameToSnapshots[name] = append(v.nameToSnapshots[name], snapshotName)
+	return nil
+}
+
+func (v *fakeVolumeDriver) Resize(name string, opts pkgmap.StringStringMap, mountpoint string, sizeBytes uint64) error {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if sizeBytes == 0 {
+		return errors.New("invalid size")
+	}
+	v.nameToSizeBytes[name] = sizeBytes
+	return nil
+}
+
+// blockMount makes mounts of name block until unblockMount is called, and
+// returns a channel that is closed once a mount is blocked.
+func (v *fakeVolumeDriver) blockMount(name string) <-chan struct{} {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	fakeMountBlock := &fakeMountBlock{
+		make(chan struct{}),
+		&sync.Once{},
+		make(chan struct{}),
+	}
+	v.nameToMountBlock[name] = fakeMountBlock
+	return fakeMountBlock.blocked
+}
+
+func (v *fakeVolumeDriver) unblockMount(name string) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	close(v.nameToMountBlock[name].release)
+	delete(v.nameToMountBlock, name)
+}
+
+func (v *fakeVolumeDriver) failCreate(name string, err error) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.nameToCreateErr[name] = err
+}
+
+// failUnmount makes unmounts of name fail with err, or succeed again if err is nil.
+func (v *fakeVolumeDriver) failUnmount(name string, err error) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if err == nil {
+		delete(v.nameToUnmountErr, name)
+		return
+	}
+	v.nameToUnmountErr[name] = err
+}
+
+func (v *fakeVolumeDriver) failList(err error) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.listErr = err
+}
+
+// loseVolume removes name from the fake backend without the API knowing.
+func (v *fakeVolumeDriver) loseVolume(name string) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	delete(v.nameToFakeVolume, name)
+}
+
+func (v *fakeVolumeDriver) hasStatus(name string) bool {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	_, ok := v.nameToFakeStatus[name]
+	return ok
+}
+
 func (v *fakeVolumeDriver) requireStatusEquals(name string, expected fakeStatus) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
 	fakeStatus, ok := v.nameToFakeStatus[name]
 	require.True(v.t, ok)
 	require.Equal(v.t, expected, fakeStatus)
 }
+
+func (v *fakeVolumeDriver) requireSnapshotsEqual(name string, expected ...string) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	require.Equal(v.t, expected, v.nameToSnapshots[name])
+}
+
+func (v *fakeVolumeDriver) requireSizeBytesEquals(name string, expected uint64) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	require.Equal(v.t, expected, v.nameToSizeBytes[name])
+}
+
+func (v *fakeVolumeDriver) requireNumMountsEquals(name string, expected int) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	require.Equal(v.t, expected, v.nameToNumMounts[name])
+}
+
+// fakeDockerClient is an in-memory DockerClient. Like docker, it refuses to
+// remove a volume used by a container, and calls the volume driver to remove a volume.
+type fakeDockerClient struct {
+	volumeDriverName string
+	nameToVolume     map[string]docker.Volume
+	containers       []docker.APIContainers
+	remove           func(string) error
+	lock             *sync.Mutex
+}
+
+func newFakeDockerClient(volumeDriverName string) *fakeDockerClient {
+	return &fakeDockerClient{
+		volumeDriverName,
+		make(map[string]docker.Volume),
+		nil,
+		nil,
+		&sync.Mutex{},
+	}
+}
+
+func (c *fakeDockerClient) ListVolumes(opts docker.ListVolumesOptions) ([]docker.Volume, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var volumes []docker.Volume
+	for _, volume := range c.nameToVolume {
+		volumes = append(volumes, volume)
+	}
+	return volumes, nil
+}
+
+func (c *fakeDockerClient) RemoveVolume(name string) error {
+	c.lock.Lock()
+	volume, ok := c.nameToVolume[name]
+	if !ok {
+		c.lock.Unlock()
+		return fmt.Errorf("no such volume: %s", name)
+	}
+	for _, container := range c.containers {
+		for _, mount := range container.Mounts {
+			if mount.Name == name {
+				c.lock.Unlock()
+				return fmt.Errorf("volume is in use: %s", name)
+			}
+		}
+	}
+	remove := c.remove
+	c.lock.Unlock()
+	if remove != nil && volume.Driver == c.volumeDriverName {
+		if err := remove(name); err != nil {
+			return err
+		}
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.nameToVolume, name)
+	return nil
+}
+
+func (c *fakeDockerClient) ListContainers(opts docker.ListContainersOptions) ([]docker.APIContainers, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]docker.APIContainers(nil), c.containers...), nil
+}
+
+// setRemove sets the function called to remove a volume of the volume driver.
+func (c *fakeDockerClient) setRemove(remove func(string) error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.remove = remove
+}
+
+func (c *fakeDockerClient) addVolume(name string, driver string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.nameToVolume[name] = docker.Volume{
+		Name:   name,
+		Driver: driver,
+	}
+}
+
+// addContainer adds a container using the given volumes of the volume driver.
+func (c *fakeDockerClient) addContainer(id string, volumeNames ...string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	container := docker.APIContainers{
+		ID: id,
+	}
+	for _, volumeName := range volumeNames {
+		container.Mounts = append(
+			container.Mounts,
+			docker.APIMount{
+				Name:   volumeName,
+				Driver: c.volumeDriverName,
+			},
+		)
+	}
+	c.containers = append(c.containers, container)
+}
+
+func (c *fakeDockerClient) volumeNames() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var names []string
+	for name := range c.nameToVolume {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type fakeWatchServer struct {
+	grpc.ServerStream
+	ctx    context.Context
+	events chan *Event
+}
+
+func (s *fakeWatchServer) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeWatchServer) Send(event *Event) error {
+	s.events <- event
+	return nil
+}
+
+// legacyVolumeDriver only exposes the VolumeDriver methods of the wrapped VolumeDriver.
+type legacyVolumeDriver struct {
+	VolumeDriver
+}
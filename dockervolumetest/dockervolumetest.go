@@ -0,0 +1,287 @@
+/*
+Package dockervolumetest provides a conformance test suite for dockervolume.VolumeDriver implementations.
+
+The suite runs a dockervolume API server for the VolumeDriver, and checks the
+semantics of the docker volume plugin API through it, once with the gRPC
+client and once with the docker volume plugin protocol, as docker calls
+plugins.
+
+	func TestConformance(t *testing.T) {
+	  dockervolumetest.RunSuite(
+		t,
+		func(dirPath string) (dockervolume.VolumeDriver, error) {
+		  return newVolumeDriver(dirPath), nil
+		},
+		dockervolumetest.SuiteOptions{},
+	  )
+	}
+
+The package also has FakeVolumeDriver, a VolumeDriver for tests of code
+that uses dockervolume, without a real storage backend.
+*/
+package dockervolumetest // import "go.pedge.io/dockervolume/dockervolumetest"
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gengo/grpc-gateway/runtime"
+	"github.com/stretchr/testify/require"
+	"go.pedge.io/dockervolume"
+	"go.pedge.io/proto/test"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+const (
+	// VolumeDriverName is the name the VolumeDriver is served with.
+	VolumeDriverName = "dockervolumetest"
+
+	defaultNumConcurrent = 8
+
+	// MethodCreate is the Create method of a VolumeDriver.
+	MethodCreate Method = "create"
+	// MethodRemove is the Remove method of a VolumeDriver.
+	MethodRemove Method = "remove"
+	// MethodMount is the Mount method of a VolumeDriver.
+	MethodMount Method = "mount"
+	// MethodUnmount is the Unmount method of a VolumeDriver.
+	MethodUnmount Method = "unmount"
+)
+
+// VolumeDriverFactory returns a new VolumeDriver without volumes. It is called once per test.
+//
+// dirPath is a new empty directory the VolumeDriver can keep its volumes in.
+// It is removed once the test is done.
+type VolumeDriverFactory func(dirPath string) (dockervolume.VolumeDriver, error)
+
+// SuiteOptions are options for RunSuite.
+type SuiteOptions struct {
+	// Opts are the opts volumes are created with, they must be valid for the VolumeDriver.
+	// If not set, volumes are created without opts.
+	Opts map[string]string
+	// RequiresOpts is set if the VolumeDriver does not create volumes without opts.
+	// If not set, the suite also checks that volumes created without opts have none.
+	RequiresOpts bool
+	// NumConcurrent is the number of goroutines for the concurrent tests.
+	// If not set, 8 goroutines are used.
+	NumConcurrent int
+}
+
+// RunSuite runs the conformance suite for the VolumeDriver returned by
+// volumeDriverFactory, with the gRPC client and the docker volume plugin
+// protocol as subtests of t.
+func RunSuite(t *testing.T, volumeDriverFactory VolumeDriverFactory, opts SuiteOptions) {
+	if opts.NumConcurrent == 0 {
+		opts.NumConcurrent = defaultNumConcurrent
+	}
+	for _, protocol := range []struct {
+		name    string
+		runFunc func(*testing.T, VolumeDriverFactory, SuiteOptions, func(*testing.T, client))
+	}{
+		{"grpc", runGRPC},
+		{"plugin", runPlugin},
+	} {
+		protocol := protocol
+		t.Run(protocol.name, func(t *testing.T) {
+			for _, test := range []struct {
+				name     string
+				testFunc func(*testing.T, client, SuiteOptions)
+			}{
+				{"Lifecycle", testLifecycle},
+				{"SharedMount", testSharedMount},
+				{"Recreate", testRecreate},
+				{"Errors", testErrors},
+				{"Opts", testOpts},
+				{"ConcurrentVolumes", testConcurrentVolumes},
+				{"ConcurrentMounts", testConcurrentMounts},
+			} {
+				test := test
+				t.Run(test.name, func(t *testing.T) {
+					protocol.runFunc(t, volumeDriverFactory, opts, func(t *testing.T, client client) {
+						test.testFunc(t, client, opts)
+					})
+				})
+			}
+		})
+	}
+}
+
+// client is the docker volume plugin API of dockervolume.VolumeDriverClient
+// and dockervolume.PluginClient.
+type client interface {
+	Create(name string, opts map[string]string) error
+	Remove(name string) error
+	Path(name string) (string, error)
+	Mount(name string, id string) (string, error)
+	Unmount(name string, id string) error
+	Get(name string) (*dockervolume.Volume, error)
+	List() ([]*dockervolume.Volume, error)
+}
+
+func runGRPC(t *testing.T, volumeDriverFactory VolumeDriverFactory, opts SuiteOptions, testFunc func(*testing.T, client)) {
+	apiServer, cleanup := newAPIServer(t, volumeDriverFactory)
+	defer cleanup()
+	prototest.RunT(
+		t,
+		1,
+		func(addressToServer map[string]*grpc.Server) {
+			for _, server := range addressToServer {
+				dockervolume.RegisterAPIServer(server, apiServer)
+			}
+		},
+		func(t *testing.T, addressToClientConn map[string]*grpc.ClientConn) {
+			for _, clientConn := range addressToClientConn {
+				testFunc(t, dockervolume.NewVolumeDriverClient(dockervolume.NewAPIClient(clientConn)))
+				return
+			}
+		},
+	)
+}
+
+// runPlugin serves the API server with the HTTP handler of the plugin on a
+// Unix socket, as docker calls plugins.
+func runPlugin(t *testing.T, volumeDriverFactory VolumeDriverFactory, opts SuiteOptions, testFunc func(*testing.T, client)) {
+	apiServer, cleanup := newAPIServer(t, volumeDriverFactory)
+	defer cleanup()
+	prototest.RunT(
+		t,
+		1,
+		func(addressToServer map[string]*grpc.Server) {
+			for _, server := range addressToServer {
+				dockervolume.RegisterAPIServer(server, apiServer)
+			}
+		},
+		func(t *testing.T, addressToClientConn map[string]*grpc.ClientConn) {
+			var clientConn *grpc.ClientConn
+			for _, cc := range addressToClientConn {
+				clientConn = cc
+				break
+			}
+			mux := runtime.NewServeMux()
+			require.NoError(t, dockervolume.RegisterAPIHandler(context.Background(), mux, clientConn))
+			dirPath, err := ioutil.TempDir("", "dockervolumetest")
+			require.NoError(t, err)
+			defer func() { _ = os.RemoveAll(dirPath) }()
+			socketPath := filepath.Join(dirPath, VolumeDriverName+".sock")
+			listener, err := net.Listen("unix", socketPath)
+			require.NoError(t, err)
+			defer func() { _ = listener.Close() }()
+			go func() { _ = http.Serve(listener, newPluginHandler(mux)) }()
+			pluginClient, err := dockervolume.NewPluginClient("unix://"+socketPath, dockervolume.PluginClientOptions{})
+			require.NoError(t, err)
+			implements, err := pluginClient.Activate()
+			require.NoError(t, err)
+			require.Equal(t, []string{"VolumeDriver"}, implements)
+			testFunc(t, pluginClient)
+		},
+	)
+}
+
+// newPluginHandler adds the activation of the plugin to the handler of the API.
+func newPluginHandler(mux *runtime.ServeMux) http.Handler {
+	serveMux := http.NewServeMux()
+	serveMux.HandleFunc(
+		"/Plugin.Activate",
+		func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/vnd.docker.plugins.v1+json")
+			_ = json.NewEncoder(w).Encode(map[string][]string{"Implements": {"VolumeDriver"}})
+		},
+	)
+	serveMux.Handle("/", mux)
+	return serveMux
+}
+
+// newAPIServer returns an APIServer for a new VolumeDriver, and a function
+// that removes the directory of the VolumeDriver.
+func newAPIServer(t *testing.T, volumeDriverFactory VolumeDriverFactory) (dockervolume.APIServer, func()) {
+	dirPath, err := ioutil.TempDir("", "dockervolumetest")
+	require.NoError(t, err)
+	cleanup := func() { _ = os.RemoveAll(dirPath) }
+	volumeDriver, err := volumeDriverFactory(dirPath)
+	if err != nil {
+		cleanup()
+		require.NoError(t, err)
+	}
+	return dockervolume.NewAPIServer(volumeDriver, VolumeDriverName), cleanup
+}
+
+// RequireRoot skips the test if it does not run as root, as mounting
+// filesystems requires.
+func RequireRoot(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("the test requires root")
+	}
+}
+
+// Method is a method of a VolumeDriver.
+type Method string
+
+// Call is a call to a FakeVolumeDriver.
+type Call struct {
+	Method Method
+	Name   string
+	Opts   map[string]string
+	// Mountpoint is the mountpoint given to Remove and Unmount, or returned by Mount.
+	Mountpoint string
+	Err        error
+}
+
+// FakeVolumeDriver is an in-memory VolumeDriver for tests. It is safe for
+// concurrent use.
+//
+// It keeps track of the volumes, and errors when it is called in a way the
+// dockervolume API should prevent, such as mounting a mounted volume. It also
+// implements dockervolume.ContextVolumeDriver, so that the latency set with
+// SetLatency ends with the context of the call, and
+// dockervolume.VolumeDriverLister.
+type FakeVolumeDriver interface {
+	dockervolume.ContextVolumeDriver
+	dockervolume.VolumeDriver
+	dockervolume.VolumeDriverLister
+
+	// SetErr makes every call of method for the volume with the given name
+	// return err. An empty name matches every volume. A nil err removes it.
+	SetErr(method Method, name string, err error)
+	// InjectErrs makes the next calls of method for the volume with the given
+	// name return errs, one per call, in order. A nil err lets the call
+	// succeed. An empty name matches every volume. Injected errors are used
+	// before the errors set with SetErr.
+	InjectErrs(method Method, name string, errs ...error)
+	// SetLatency makes every call of method wait for latency first.
+	SetLatency(method Method, latency time.Duration)
+	// Calls returns the calls so far, in order.
+	Calls() []*Call
+	// Reset forgets the calls, errors and latencies, but not the volumes.
+	Reset()
+	// IsMounted returns whether the volume with the given name is mounted.
+	IsMounted(name string) bool
+
+	// RequireCalls requires the methods and names of the calls so far to be the given ones.
+	RequireCalls(t *testing.T, expected ...Call)
+	// RequireVolumes requires the volumes to be the ones with the given names.
+	RequireVolumes(t *testing.T, names ...string)
+	// RequireMounted requires the volume with the given name to be mounted.
+	RequireMounted(t *testing.T, name string)
+	// RequireNotMounted requires the volume with the given name to exist and not be mounted.
+	RequireNotMounted(t *testing.T, name string)
+}
+
+// FakeVolumeDriverOptions are options for a FakeVolumeDriver.
+type FakeVolumeDriverOptions struct {
+	// MountpointPrefix is the directory of the mountpoints, the mountpoint
+	// of a volume is MountpointPrefix/name. Nothing is created there.
+	// If not set, /mnt is used.
+	MountpointPrefix string
+}
+
+// NewFakeVolumeDriver returns a new FakeVolumeDriver without volumes.
+func NewFakeVolumeDriver(opts FakeVolumeDriverOptions) FakeVolumeDriver {
+	return newFakeVolumeDriver(opts)
+}
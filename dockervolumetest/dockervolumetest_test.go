@@ -0,0 +1,115 @@
+package dockervolumetest
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.pedge.io/dockervolume"
+	"golang.org/x/net/context"
+)
+
+func TestSuite(t *testing.T) {
+	RunSuite(
+		t,
+		func(string) (dockervolume.VolumeDriver, error) {
+			return NewFakeVolumeDriver(FakeVolumeDriverOptions{}), nil
+		},
+		SuiteOptions{
+			Opts: map[string]string{"key": "value"},
+		},
+	)
+}
+
+func TestFakeVolumeDriver(t *testing.T) {
+	fakeVolumeDriver := NewFakeVolumeDriver(FakeVolumeDriverOptions{MountpointPrefix: "/tmp/volumes"})
+	require.NoError(t, fakeVolumeDriver.Create("foo", map[string]string{"key": "value"}))
+	require.Error(t, fakeVolumeDriver.Create("foo", nil))
+	mountpoint, err := fakeVolumeDriver.Mount("foo", nil)
+	require.NoError(t, err)
+	require.Equal(t, "/tmp/volumes/foo", mountpoint)
+	fakeVolumeDriver.RequireMounted(t, "foo")
+	require.Error(t, fakeVolumeDriver.Remove("foo", nil, mountpoint))
+	require.Error(t, fakeVolumeDriver.Unmount("foo", nil, "/tmp/volumes/bar"))
+	require.NoError(t, fakeVolumeDriver.Unmount("foo", nil, mountpoint))
+	fakeVolumeDriver.RequireNotMounted(t, "foo")
+	names, err := fakeVolumeDriver.ListVolumeNames()
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo"}, names)
+	fakeVolumeDriver.RequireCalls(
+		t,
+		Call{Method: MethodCreate, Name: "foo"},
+		Call{Method: MethodCreate, Name: "foo"},
+		Call{Method: MethodMount, Name: "foo"},
+		Call{Method: MethodRemove, Name: "foo"},
+		Call{Method: MethodUnmount, Name: "foo"},
+		Call{Method: MethodUnmount, Name: "foo"},
+	)
+	calls := fakeVolumeDriver.Calls()
+	require.Equal(t, map[string]string{"key": "value"}, calls[0].Opts)
+	require.NoError(t, calls[0].Err)
+	require.Error(t, calls[1].Err)
+	require.Equal(t, "/tmp/volumes/foo", calls[2].Mountpoint)
+	fakeVolumeDriver.Reset()
+	fakeVolumeDriver.RequireCalls(t)
+	fakeVolumeDriver.RequireVolumes(t, "foo")
+}
+
+func TestFakeVolumeDriverErrs(t *testing.T) {
+	fakeVolumeDriver := NewFakeVolumeDriver(FakeVolumeDriverOptions{})
+	first := errors.New("first")
+	second := errors.New("second")
+	always := errors.New("always")
+	fakeVolumeDriver.SetErr(MethodCreate, "", always)
+	fakeVolumeDriver.InjectErrs(MethodCreate, "foo", first, nil, second)
+	require.Equal(t, first, fakeVolumeDriver.Create("foo", nil))
+	require.NoError(t, fakeVolumeDriver.Create("foo", nil))
+	require.Equal(t, second, fakeVolumeDriver.Create("foo", nil))
+	require.Equal(t, always, fakeVolumeDriver.Create("foo", nil))
+	require.Equal(t, always, fakeVolumeDriver.Create("bar", nil))
+	fakeVolumeDriver.SetErr(MethodCreate, "", nil)
+	require.NoError(t, fakeVolumeDriver.Create("bar", nil))
+	fakeVolumeDriver.RequireVolumes(t, "bar", "foo")
+
+	// through the API, the error of the VolumeDriver is a DriverError
+	apiServer := dockervolume.NewAPIServer(fakeVolumeDriver, VolumeDriverName)
+	fakeVolumeDriver.InjectErrs(MethodMount, "baz", first)
+	_, err := apiServer.Create(context.Background(), &dockervolume.NameOptsRequest{Name: "baz"})
+	require.NoError(t, err)
+	response, err := apiServer.Mount(context.Background(), &dockervolume.NameIDRequest{Name: "baz", Id: "a"})
+	require.NoError(t, err)
+	require.Contains(t, response.Err, "first")
+	fakeVolumeDriver.RequireNotMounted(t, "baz")
+}
+
+func TestFakeVolumeDriverLatency(t *testing.T) {
+	fakeVolumeDriver := NewFakeVolumeDriver(FakeVolumeDriverOptions{})
+	require.NoError(t, fakeVolumeDriver.Create("foo", nil))
+	fakeVolumeDriver.SetLatency(MethodMount, time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := fakeVolumeDriver.MountContext(ctx, "foo", nil)
+	require.Equal(t, context.DeadlineExceeded, err)
+	fakeVolumeDriver.RequireNotMounted(t, "foo")
+
+	fakeVolumeDriver.SetLatency(MethodMount, 10*time.Millisecond)
+	var waitGroup sync.WaitGroup
+	start := time.Now()
+	for _, name := range []string{"bar", "baz"} {
+		require.NoError(t, fakeVolumeDriver.Create(name, nil))
+		name := name
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			if _, err := fakeVolumeDriver.Mount(name, nil); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	waitGroup.Wait()
+	require.True(t, time.Since(start) >= 10*time.Millisecond)
+	fakeVolumeDriver.RequireMounted(t, "bar")
+	fakeVolumeDriver.RequireMounted(t, "baz")
+}
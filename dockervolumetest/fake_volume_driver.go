@@ -0,0 +1,296 @@
+package dockervolumetest
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.pedge.io/pkg/map"
+	"golang.org/x/net/context"
+)
+
+const defaultMountpointPrefix = "/mnt"
+
+type methodName struct {
+	method Method
+	name   string
+}
+
+type fakeVolumeDriver struct {
+	mountpointPrefix string
+	nameToMountpoint map[string]string
+	methodNameToErr  map[methodName]error
+	methodNameToErrs map[methodName][]error
+	methodToLatency  map[Method]time.Duration
+	calls            []*Call
+	lock             *sync.Mutex
+}
+
+func newFakeVolumeDriver(opts FakeVolumeDriverOptions) *fakeVolumeDriver {
+	mountpointPrefix := opts.MountpointPrefix
+	if mountpointPrefix == "" {
+		mountpointPrefix = defaultMountpointPrefix
+	}
+	return &fakeVolumeDriver{
+		mountpointPrefix,
+		make(map[string]string),
+		make(map[methodName]error),
+		make(map[methodName][]error),
+		make(map[Method]time.Duration),
+		nil,
+		&sync.Mutex{},
+	}
+}
+
+func (f *fakeVolumeDriver) Create(name string, opts pkgmap.StringStringMap) error {
+	return f.CreateContext(context.Background(), name, opts)
+}
+
+func (f *fakeVolumeDriver) Remove(name string, opts pkgmap.StringStringMap, mountpoint string) error {
+	return f.RemoveContext(context.Background(), name, opts, mountpoint)
+}
+
+func (f *fakeVolumeDriver) Mount(name string, opts pkgmap.StringStringMap) (string, error) {
+	return f.MountContext(context.Background(), name, opts)
+}
+
+func (f *fakeVolumeDriver) Unmount(name string, opts pkgmap.StringStringMap, mountpoint string) error {
+	return f.UnmountContext(context.Background(), name, opts, mountpoint)
+}
+
+func (f *fakeVolumeDriver) CreateContext(ctx context.Context, name string, opts pkgmap.StringStringMap) error {
+	_, err := f.call(ctx, MethodCreate, name, opts, "", func() (string, error) {
+		if _, ok := f.nameToMountpoint[name]; ok {
+			return "", fmt.Errorf("dockervolumetest: create %s: volume exists", name)
+		}
+		f.nameToMountpoint[name] = ""
+		return "", nil
+	})
+	return err
+}
+
+func (f *fakeVolumeDriver) RemoveContext(ctx context.Context, name string, opts pkgmap.StringStringMap, mountpoint string) error {
+	_, err := f.call(ctx, MethodRemove, name, opts, mountpoint, func() (string, error) {
+		currentMountpoint, ok := f.nameToMountpoint[name]
+		if !ok {
+			return "", fmt.Errorf("dockervolumetest: remove %s: volume not found", name)
+		}
+		if currentMountpoint != "" {
+			return "", fmt.Errorf("dockervolumetest: remove %s: volume mounted on %s", name, currentMountpoint)
+		}
+		delete(f.nameToMountpoint, name)
+		return "", nil
+	})
+	return err
+}
+
+func (f *fakeVolumeDriver) MountContext(ctx context.Context, name string, opts pkgmap.StringStringMap) (string, error) {
+	return f.call(ctx, MethodMount, name, opts, "", func() (string, error) {
+		currentMountpoint, ok := f.nameToMountpoint[name]
+		if !ok {
+			return "", fmt.Errorf("dockervolumetest: mount %s: volume not found", name)
+		}
+		if currentMountpoint != "" {
+			return "", fmt.Errorf("dockervolumetest: mount %s: volume already mounted on %s", name, currentMountpoint)
+		}
+		mountpoint := filepath.Join(f.mountpointPrefix, name)
+		f.nameToMountpoint[name] = mountpoint
+		return mountpoint, nil
+	})
+}
+
+func (f *fakeVolumeDriver) UnmountContext(ctx context.Context, name string, opts pkgmap.StringStringMap, mountpoint string) error {
+	_, err := f.call(ctx, MethodUnmount, name, opts, mountpoint, func() (string, error) {
+		currentMountpoint, ok := f.nameToMountpoint[name]
+		if !ok {
+			return "", fmt.Errorf("dockervolumetest: unmount %s: volume not found", name)
+		}
+		if currentMountpoint == "" || currentMountpoint != mountpoint {
+			return "", fmt.Errorf("dockervolumetest: unmount %s: volume not mounted on %s", name, mountpoint)
+		}
+		f.nameToMountpoint[name] = ""
+		return "", nil
+	})
+	return err
+}
+
+func (f *fakeVolumeDriver) ListVolumeNames() ([]string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.volumeNames(), nil
+}
+
+func (f *fakeVolumeDriver) SetErr(method Method, name string, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if err == nil {
+		delete(f.methodNameToErr, methodName{method, name})
+		return
+	}
+	f.methodNameToErr[methodName{method, name}] = err
+}
+
+func (f *fakeVolumeDriver) InjectErrs(method Method, name string, errs ...error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	key := methodName{method, name}
+	f.methodNameToErrs[key] = append(f.methodNameToErrs[key], errs...)
+}
+
+func (f *fakeVolumeDriver) SetLatency(method Method, latency time.Duration) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.methodToLatency[method] = latency
+}
+
+func (f *fakeVolumeDriver) Calls() []*Call {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	calls := make([]*Call, len(f.calls))
+	for i, call := range f.calls {
+		calls[i] = copyCall(call)
+	}
+	return calls
+}
+
+func (f *fakeVolumeDriver) Reset() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.methodNameToErr = make(map[methodName]error)
+	f.methodNameToErrs = make(map[methodName][]error)
+	f.methodToLatency = make(map[Method]time.Duration)
+	f.calls = nil
+}
+
+func (f *fakeVolumeDriver) IsMounted(name string) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.nameToMountpoint[name] != ""
+}
+
+func (f *fakeVolumeDriver) RequireCalls(t *testing.T, expected ...Call) {
+	calls := f.Calls()
+	actual := make([]string, len(calls))
+	for i, call := range calls {
+		actual[i] = fmt.Sprintf("%s %s", call.Method, call.Name)
+	}
+	expectedStrings := make([]string, len(expected))
+	for i, call := range expected {
+		expectedStrings[i] = fmt.Sprintf("%s %s", call.Method, call.Name)
+	}
+	require.Equal(t, expectedStrings, actual)
+}
+
+func (f *fakeVolumeDriver) RequireVolumes(t *testing.T, names ...string) {
+	f.lock.Lock()
+	actual := f.volumeNames()
+	f.lock.Unlock()
+	expected := append([]string{}, names...)
+	sort.Strings(expected)
+	require.Equal(t, expected, actual)
+}
+
+func (f *fakeVolumeDriver) RequireMounted(t *testing.T, name string) {
+	require.True(t, f.IsMounted(name), "volume %s is not mounted", name)
+}
+
+func (f *fakeVolumeDriver) RequireNotMounted(t *testing.T, name string) {
+	f.lock.Lock()
+	mountpoint, ok := f.nameToMountpoint[name]
+	f.lock.Unlock()
+	require.True(t, ok, "volume %s does not exist", name)
+	require.Equal(t, "", mountpoint, "volume %s is mounted", name)
+}
+
+// call waits for the latency of method, then runs f with the lock held
+// unless an error is set for the call, and records the call.
+func (f *fakeVolumeDriver) call(
+	ctx context.Context,
+	method Method,
+	name string,
+	opts pkgmap.StringStringMap,
+	mountpoint string,
+	do func() (string, error),
+) (string, error) {
+	f.lock.Lock()
+	latency := f.methodToLatency[method]
+	f.lock.Unlock()
+	var err error
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if err == nil {
+		err = f.getErr(method, name)
+	}
+	if err == nil {
+		var returnedMountpoint string
+		returnedMountpoint, err = do()
+		if method == MethodMount {
+			mountpoint = returnedMountpoint
+		}
+	}
+	f.calls = append(
+		f.calls,
+		&Call{
+			Method:     method,
+			Name:       name,
+			Opts:       opts.Copy(),
+			Mountpoint: mountpoint,
+			Err:        err,
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+	return mountpoint, nil
+}
+
+// getErr returns the error for a call, the lock must be held.
+func (f *fakeVolumeDriver) getErr(method Method, name string) error {
+	for _, key := range []methodName{{method, name}, {method, ""}} {
+		if errs := f.methodNameToErrs[key]; len(errs) > 0 {
+			f.methodNameToErrs[key] = errs[1:]
+			return errs[0]
+		}
+	}
+	for _, key := range []methodName{{method, name}, {method, ""}} {
+		if err, ok := f.methodNameToErr[key]; ok {
+			return err
+		}
+	}
+	return nil
+}
+
+// volumeNames returns the sorted names of the volumes, the lock must be held.
+func (f *fakeVolumeDriver) volumeNames() []string {
+	names := make([]string, 0, len(f.nameToMountpoint))
+	for name := range f.nameToMountpoint {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func copyCall(call *Call) *Call {
+	opts := make(map[string]string, len(call.Opts))
+	for key, value := range call.Opts {
+		opts[key] = value
+	}
+	return &Call{
+		Method:     call.Method,
+		Name:       call.Name,
+		Opts:       opts,
+		Mountpoint: call.Mountpoint,
+		Err:        call.Err,
+	}
+}
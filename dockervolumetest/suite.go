@@ -0,0 +1,233 @@
+package dockervolumetest
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.pedge.io/dockervolume"
+)
+
+func testLifecycle(t *testing.T, client client, opts SuiteOptions) {
+	require.NoError(t, client.Create("foo", opts.Opts))
+	volume, err := client.Get("foo")
+	require.NoError(t, err)
+	require.Equal(t, "foo", volume.Name)
+	require.Equal(t, "", volume.Mountpoint)
+	requireVolumeNames(t, client, "foo")
+	mountpoint, err := client.Path("foo")
+	require.NoError(t, err)
+	require.Equal(t, "", mountpoint)
+	mountpoint, err = client.Mount("foo", "a")
+	require.NoError(t, err)
+	require.NotEqual(t, "", mountpoint, "the VolumeDriver returned an empty mountpoint")
+	path, err := client.Path("foo")
+	require.NoError(t, err)
+	require.Equal(t, mountpoint, path)
+	volume, err = client.Get("foo")
+	require.NoError(t, err)
+	require.Equal(t, mountpoint, volume.Mountpoint)
+	require.NoError(t, client.Unmount("foo", "a"))
+	path, err = client.Path("foo")
+	require.NoError(t, err)
+	require.Equal(t, "", path)
+	require.NoError(t, client.Remove("foo"))
+	_, err = client.Get("foo")
+	requireIs(t, err, dockervolume.ErrVolumeNotFound)
+	requireVolumeNames(t, client)
+}
+
+func testSharedMount(t *testing.T, client client, opts SuiteOptions) {
+	require.NoError(t, client.Create("foo", opts.Opts))
+	mountpoint, err := client.Mount("foo", "a")
+	require.NoError(t, err)
+	// every caller shares the mountpoint
+	shared, err := client.Mount("foo", "b")
+	require.NoError(t, err)
+	require.Equal(t, mountpoint, shared)
+	// mounting again with the same id is a no-op
+	shared, err = client.Mount("foo", "b")
+	require.NoError(t, err)
+	require.Equal(t, mountpoint, shared)
+	require.NoError(t, client.Unmount("foo", "a"))
+	requireIs(t, client.Unmount("foo", "a"), dockervolume.ErrNotMounted)
+	// the volume stays mounted until the last caller unmounts it
+	path, err := client.Path("foo")
+	require.NoError(t, err)
+	require.Equal(t, mountpoint, path)
+	requireIs(t, client.Remove("foo"), dockervolume.ErrAlreadyMounted)
+	require.NoError(t, client.Unmount("foo", "b"))
+	path, err = client.Path("foo")
+	require.NoError(t, err)
+	require.Equal(t, "", path)
+	require.NoError(t, client.Remove("foo"))
+}
+
+func testRecreate(t *testing.T, client client, opts SuiteOptions) {
+	for i := 0; i < 2; i++ {
+		require.NoError(t, client.Create("foo", opts.Opts))
+		// a volume can be mounted again after it was unmounted
+		for j := 0; j < 2; j++ {
+			_, err := client.Mount("foo", "a")
+			require.NoError(t, err)
+			require.NoError(t, client.Unmount("foo", "a"))
+		}
+		require.NoError(t, client.Remove("foo"))
+	}
+	requireVolumeNames(t, client)
+}
+
+func testErrors(t *testing.T, client client, opts SuiteOptions) {
+	requireIs(t, client.Remove("foo"), dockervolume.ErrVolumeNotFound)
+	_, err := client.Path("foo")
+	requireIs(t, err, dockervolume.ErrVolumeNotFound)
+	_, err = client.Mount("foo", "a")
+	requireIs(t, err, dockervolume.ErrVolumeNotFound)
+	requireIs(t, client.Unmount("foo", "a"), dockervolume.ErrVolumeNotFound)
+	_, err = client.Get("foo")
+	requireIs(t, err, dockervolume.ErrVolumeNotFound)
+	require.NoError(t, client.Create("foo", opts.Opts))
+	requireIs(t, client.Create("foo", opts.Opts), dockervolume.ErrVolumeExists)
+	requireIs(t, client.Unmount("foo", "a"), dockervolume.ErrNotMounted)
+	_, err = client.Mount("foo", "a")
+	require.NoError(t, err)
+	requireIs(t, client.Remove("foo"), dockervolume.ErrAlreadyMounted)
+	require.NoError(t, client.Unmount("foo", "a"))
+	require.NoError(t, client.Remove("foo"))
+}
+
+// testOpts checks the opts are kept for volumes, and that volumes created
+// without opts have none, unless the VolumeDriver requires opts. The docker
+// volume plugin protocol has no opts in responses, so they are only checked
+// with the gRPC client.
+func testOpts(t *testing.T, client client, opts SuiteOptions) {
+	require.NoError(t, client.Create("foo", opts.Opts))
+	if !opts.RequiresOpts {
+		require.NoError(t, client.Create("bar", nil))
+	}
+	volumeDriverClient, ok := client.(dockervolume.VolumeDriverClient)
+	if !ok {
+		return
+	}
+	volume, err := volumeDriverClient.GetVolume("foo")
+	require.NoError(t, err)
+	requireOptsEqual(t, opts.Opts, volume.Opts)
+	_, err = volumeDriverClient.Mount("foo", "a")
+	require.NoError(t, err)
+	volume, err = volumeDriverClient.GetVolume("foo")
+	require.NoError(t, err)
+	requireOptsEqual(t, opts.Opts, volume.Opts)
+	if !opts.RequiresOpts {
+		volume, err = volumeDriverClient.GetVolume("bar")
+		require.NoError(t, err)
+		requireOptsEqual(t, nil, volume.Opts)
+	}
+	require.NoError(t, volumeDriverClient.Unmount("foo", "a"))
+}
+
+func testConcurrentVolumes(t *testing.T, client client, opts SuiteOptions) {
+	var waitGroup sync.WaitGroup
+	errC := make(chan error, opts.NumConcurrent)
+	for i := 0; i < opts.NumConcurrent; i++ {
+		name := fmt.Sprintf("foo-%d", i)
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			errC <- createMountUnmountRemove(client, name, opts.Opts)
+		}()
+	}
+	waitGroup.Wait()
+	close(errC)
+	for err := range errC {
+		require.NoError(t, err)
+	}
+	requireVolumeNames(t, client)
+}
+
+func testConcurrentMounts(t *testing.T, client client, opts SuiteOptions) {
+	require.NoError(t, client.Create("foo", opts.Opts))
+	var waitGroup sync.WaitGroup
+	mountpointC := make(chan string, opts.NumConcurrent)
+	errC := make(chan error, opts.NumConcurrent)
+	for i := 0; i < opts.NumConcurrent; i++ {
+		id := fmt.Sprintf("id-%d", i)
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			mountpoint, err := client.Mount("foo", id)
+			if err != nil {
+				errC <- err
+				return
+			}
+			mountpointC <- mountpoint
+		}()
+	}
+	waitGroup.Wait()
+	close(mountpointC)
+	close(errC)
+	for err := range errC {
+		require.NoError(t, err)
+	}
+	mountpoints := make(map[string]bool)
+	for mountpoint := range mountpointC {
+		mountpoints[mountpoint] = true
+	}
+	require.Len(t, mountpoints, 1, "concurrent callers got different mountpoints")
+	for i := 0; i < opts.NumConcurrent; i++ {
+		id := fmt.Sprintf("id-%d", i)
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			if err := client.Unmount("foo", id); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	waitGroup.Wait()
+	path, err := client.Path("foo")
+	require.NoError(t, err)
+	require.Equal(t, "", path)
+	require.NoError(t, client.Remove("foo"))
+}
+
+func createMountUnmountRemove(client client, name string, opts map[string]string) error {
+	if err := client.Create(name, opts); err != nil {
+		return err
+	}
+	if _, err := client.Mount(name, "a"); err != nil {
+		return err
+	}
+	if err := client.Unmount(name, "a"); err != nil {
+		return err
+	}
+	return client.Remove(name)
+}
+
+func requireVolumeNames(t *testing.T, client client, expected ...string) {
+	volumes, err := client.List()
+	require.NoError(t, err)
+	names := make([]string, len(volumes))
+	for i, volume := range volumes {
+		names[i] = volume.Name
+	}
+	sort.Strings(names)
+	sort.Strings(expected)
+	require.Equal(t, len(expected), len(names), "volumes %v, expected %v", names, expected)
+	for i, name := range expected {
+		require.Equal(t, name, names[i])
+	}
+}
+
+func requireOptsEqual(t *testing.T, expected map[string]string, actual map[string]string) {
+	require.Equal(t, len(expected), len(actual), "opts %v, expected %v", actual, expected)
+	for key, value := range expected {
+		require.Equal(t, value, actual[key], "opts %v, expected %v", actual, expected)
+	}
+}
+
+func requireIs(t *testing.T, err error, expected error) {
+	require.True(t, errors.Is(err, expected), "error %v, expected %v", err, expected)
+}
@@ -0,0 +1,42 @@
+/*
+Package bind implements a dockervolume.VolumeDriver that exposes existing host directories as volumes with bind mounts.
+
+The host directories are restricted to the allowed paths given when the
+VolumeDriver is created. The host directory of a volume is resolved with
+its symlinks on Create and again on every Mount, and a directory outside of
+the allowed paths is rejected, so that a symlink cannot escape them. The host
+directory is never deleted, Remove only forgets the volume.
+
+The opts of a volume are:
+
+	path      the absolute path of the host directory, required
+	readonly  true to bind mount the directory read-only, by default false
+
+Any other opt is an error. Mounting volumes is only supported on Linux, and requires root.
+*/
+package bind // import "go.pedge.io/dockervolume/driver/bind"
+
+import (
+	"go.pedge.io/dockervolume"
+)
+
+const (
+	// OptPath is the opt for the absolute path of the host directory.
+	OptPath = "path"
+	// OptReadOnly is the opt to bind mount the host directory read-only.
+	OptReadOnly = "readonly"
+)
+
+// VolumeDriverOptions are options for a VolumeDriver.
+type VolumeDriverOptions struct {
+	// AllowedPaths are the absolute paths of the directories that contain
+	// the host directories volumes can expose. At least one is required.
+	AllowedPaths []string
+}
+
+// NewVolumeDriver returns a new dockervolume.VolumeDriver that bind mounts
+// the host directories of the volumes on directories named after the volumes
+// under baseDirPath. baseDirPath is created if it does not exist.
+func NewVolumeDriver(baseDirPath string, opts VolumeDriverOptions) (dockervolume.VolumeDriver, error) {
+	return newVolumeDriver(baseDirPath, opts)
+}
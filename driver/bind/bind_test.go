@@ -0,0 +1,126 @@
+package bind
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.pedge.io/dockervolume"
+	"go.pedge.io/dockervolume/dockervolumetest"
+)
+
+func TestSuite(t *testing.T) {
+	dockervolumetest.RequireRoot(t)
+	allowedPath, hostPath := newTestHostPath(t)
+	defer func() { _ = os.RemoveAll(allowedPath) }()
+	dockervolumetest.RunSuite(
+		t,
+		func(dirPath string) (dockervolume.VolumeDriver, error) {
+			return NewVolumeDriver(dirPath, VolumeDriverOptions{AllowedPaths: []string{allowedPath}})
+		},
+		dockervolumetest.SuiteOptions{
+			Opts:         map[string]string{OptPath: hostPath},
+			RequiresOpts: true,
+		},
+	)
+}
+
+func TestMount(t *testing.T) {
+	dockervolumetest.RequireRoot(t)
+	allowedPath, hostPath := newTestHostPath(t)
+	defer func() { _ = os.RemoveAll(allowedPath) }()
+	volumeDriver, dirPath := newTestVolumeDriver(t, allowedPath)
+	defer func() { _ = os.RemoveAll(dirPath) }()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(hostPath, "file"), []byte("data"), 0644))
+
+	opts := map[string]string{OptPath: hostPath}
+	require.NoError(t, volumeDriver.Create("foo", opts))
+	mountpoint, err := volumeDriver.Mount("foo", opts)
+	require.NoError(t, err)
+	data, err := ioutil.ReadFile(filepath.Join(mountpoint, "file"))
+	require.NoError(t, err)
+	require.Equal(t, "data", string(data))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(mountpoint, "other"), []byte("other"), 0644))
+	require.NoError(t, volumeDriver.Unmount("foo", opts, mountpoint))
+	require.NoError(t, volumeDriver.Remove("foo", opts, ""))
+	// the host directory is kept
+	data, err = ioutil.ReadFile(filepath.Join(hostPath, "other"))
+	require.NoError(t, err)
+	require.Equal(t, "other", string(data))
+
+	opts = map[string]string{OptPath: hostPath, OptReadOnly: "true"}
+	require.NoError(t, volumeDriver.Create("bar", opts))
+	mountpoint, err = volumeDriver.Mount("bar", opts)
+	require.NoError(t, err)
+	_, err = ioutil.ReadFile(filepath.Join(mountpoint, "file"))
+	require.NoError(t, err)
+	require.Error(t, ioutil.WriteFile(filepath.Join(mountpoint, "readonly"), []byte("data"), 0644))
+	require.NoError(t, volumeDriver.Unmount("bar", opts, mountpoint))
+}
+
+func TestPolicy(t *testing.T) {
+	allowedPath, hostPath := newTestHostPath(t)
+	defer func() { _ = os.RemoveAll(allowedPath) }()
+	outsidePath, err := ioutil.TempDir("", "bind")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(outsidePath) }()
+	volumeDriver, dirPath := newTestVolumeDriver(t, allowedPath)
+	defer func() { _ = os.RemoveAll(dirPath) }()
+	require.NoError(t, os.Symlink(outsidePath, filepath.Join(allowedPath, "escape")))
+	require.NoError(t, os.Symlink(hostPath, filepath.Join(allowedPath, "inside")))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(allowedPath, "file"), nil, 0644))
+	require.NoError(t, os.Mkdir(allowedPath+"-sibling", 0755))
+	defer func() { _ = os.RemoveAll(allowedPath + "-sibling") }()
+
+	for _, path := range []string{allowedPath, hostPath, filepath.Join(allowedPath, "inside")} {
+		require.NoError(t, volumeDriver.Create("foo", map[string]string{OptPath: path}), path)
+	}
+	for _, opts := range []map[string]string{
+		nil,
+		{OptPath: outsidePath},
+		{OptPath: filepath.Join(allowedPath, "escape")},
+		{OptPath: filepath.Join(allowedPath, "..")},
+		{OptPath: allowedPath + "-sibling"},
+		{OptPath: filepath.Join(allowedPath, "file")},
+		{OptPath: filepath.Join(allowedPath, "missing")},
+		{OptPath: "data"},
+		{OptPath: hostPath, OptReadOnly: "yes please"},
+		{OptPath: hostPath, "mode": "0755"},
+	} {
+		require.Error(t, volumeDriver.Create("foo", opts), "%v", opts)
+	}
+	for _, name := range []string{"", "..", "foo/bar"} {
+		require.Error(t, volumeDriver.Create(name, map[string]string{OptPath: hostPath}), "%q", name)
+	}
+	// a symlink replaced after Create is rejected on Mount
+	require.NoError(t, os.Remove(filepath.Join(allowedPath, "inside")))
+	require.NoError(t, os.Symlink(outsidePath, filepath.Join(allowedPath, "inside")))
+	_, err = volumeDriver.Mount("foo", map[string]string{OptPath: filepath.Join(allowedPath, "inside")})
+	require.Error(t, err)
+
+	_, err = NewVolumeDriver(dirPath, VolumeDriverOptions{})
+	require.Error(t, err)
+	_, err = NewVolumeDriver(dirPath, VolumeDriverOptions{AllowedPaths: []string{"relative"}})
+	require.Error(t, err)
+}
+
+// newTestHostPath returns an allowed path, and a host directory within it.
+func newTestHostPath(t *testing.T) (string, string) {
+	allowedPath, err := ioutil.TempDir("", "bind")
+	require.NoError(t, err)
+	allowedPath, err = filepath.EvalSymlinks(allowedPath)
+	require.NoError(t, err)
+	hostPath := filepath.Join(allowedPath, "data")
+	require.NoError(t, os.Mkdir(hostPath, 0755))
+	return allowedPath, hostPath
+}
+
+func newTestVolumeDriver(t *testing.T, allowedPath string) (dockervolume.VolumeDriver, string) {
+	dirPath, err := ioutil.TempDir("", "bind")
+	require.NoError(t, err)
+	volumeDriver, err := NewVolumeDriver(dirPath, VolumeDriverOptions{AllowedPaths: []string{allowedPath}})
+	require.NoError(t, err)
+	return volumeDriver, dirPath
+}
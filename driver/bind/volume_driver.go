@@ -0,0 +1,188 @@
+package bind
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go.pedge.io/dockervolume/driver/internal"
+	"go.pedge.io/pkg/map"
+)
+
+type volumeDriver struct {
+	baseDirPath  string
+	allowedPaths []string
+}
+
+func newVolumeDriver(baseDirPath string, opts VolumeDriverOptions) (*volumeDriver, error) {
+	if len(opts.AllowedPaths) == 0 {
+		return nil, errors.New("bind: at least one allowed path is required")
+	}
+	allowedPaths := make([]string, len(opts.AllowedPaths))
+	for i, allowedPath := range opts.AllowedPaths {
+		if !filepath.IsAbs(allowedPath) {
+			return nil, fmt.Errorf("bind: allowed path must be absolute: %s", allowedPath)
+		}
+		// compare real paths, as the host directories are resolved with their symlinks
+		realPath, err := filepath.EvalSymlinks(allowedPath)
+		if err != nil {
+			return nil, err
+		}
+		allowedPaths[i] = realPath
+	}
+	if err := os.MkdirAll(baseDirPath, 0755); err != nil {
+		return nil, err
+	}
+	return &volumeDriver{
+		baseDirPath,
+		allowedPaths,
+	}, nil
+}
+
+func (v *volumeDriver) Create(name string, opts pkgmap.StringStringMap) error {
+	if err := internal.CheckName("bind", name); err != nil {
+		return err
+	}
+	volumeOpts, err := parseVolumeOpts(opts)
+	if err != nil {
+		return err
+	}
+	_, err = v.resolvePath(volumeOpts.path)
+	return err
+}
+
+func (v *volumeDriver) Remove(name string, _ pkgmap.StringStringMap, _ string) error {
+	// the host directory belongs to the host, it is never deleted
+	return internal.CheckName("bind", name)
+}
+
+func (v *volumeDriver) Mount(name string, opts pkgmap.StringStringMap) (string, error) {
+	if err := internal.CheckName("bind", name); err != nil {
+		return "", err
+	}
+	volumeOpts, err := parseVolumeOpts(opts)
+	if err != nil {
+		return "", err
+	}
+	// the host directory is resolved again, a symlink could have changed since Create
+	realPath, err := v.resolvePath(volumeOpts.path)
+	if err != nil {
+		return "", err
+	}
+	// the open directory is checked and mounted instead of the path, as a
+	// symlink could be swapped into the path after it was resolved
+	dir, err := internal.OpenDir(realPath)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = dir.Close() }()
+	dirPath, err := os.Readlink(internal.FilePath(dir))
+	if err != nil {
+		return "", err
+	}
+	if err := v.checkAllowed(volumeOpts.path, dirPath); err != nil {
+		return "", err
+	}
+	mountpoint := filepath.Join(v.baseDirPath, name)
+	if err := os.MkdirAll(mountpoint, 0755); err != nil {
+		return "", err
+	}
+	if err := bindMount(internal.FilePath(dir), mountpoint, volumeOpts.readOnly); err != nil {
+		_ = os.Remove(mountpoint)
+		return "", err
+	}
+	return mountpoint, nil
+}
+
+func (v *volumeDriver) Unmount(name string, _ pkgmap.StringStringMap, mountpoint string) error {
+	if err := internal.CheckName("bind", name); err != nil {
+		return err
+	}
+	if err := internal.Unmount(mountpoint); err != nil {
+		return err
+	}
+	return os.Remove(mountpoint)
+}
+
+// resolvePath returns the real path of the host directory, and errors if it
+// is not a directory within the allowed paths.
+func (v *volumeDriver) resolvePath(path string) (string, error) {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	fileInfo, err := os.Stat(realPath)
+	if err != nil {
+		return "", err
+	}
+	if !fileInfo.IsDir() {
+		return "", fmt.Errorf("bind: %s is not a directory", path)
+	}
+	if err := v.checkAllowed(path, realPath); err != nil {
+		return "", err
+	}
+	return realPath, nil
+}
+
+// checkAllowed errors if realPath, the real path of path, is not within the allowed paths.
+func (v *volumeDriver) checkAllowed(path string, realPath string) error {
+	for _, allowedPath := range v.allowedPaths {
+		if isWithin(allowedPath, realPath) {
+			return nil
+		}
+	}
+	return fmt.Errorf("bind: %s is not within the allowed paths %s", path, strings.Join(v.allowedPaths, ", "))
+}
+
+// isWithin returns whether path is dirPath or within dirPath.
+func isWithin(dirPath string, path string) bool {
+	return path == dirPath || strings.HasPrefix(path, strings.TrimSuffix(dirPath, string(filepath.Separator))+string(filepath.Separator))
+}
+
+type volumeOpts struct {
+	path     string
+	readOnly bool
+}
+
+func parseVolumeOpts(opts pkgmap.StringStringMap) (*volumeOpts, error) {
+	volumeOpts := &volumeOpts{}
+	for key, value := range opts {
+		switch key {
+		case OptPath:
+			if !filepath.IsAbs(value) {
+				return nil, fmt.Errorf("bind: invalid %s, must be absolute: %s", key, value)
+			}
+			volumeOpts.path = filepath.Clean(value)
+		case OptReadOnly:
+			readOnly, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("bind: invalid %s, must be true or false: %s", key, value)
+			}
+			volumeOpts.readOnly = readOnly
+		default:
+			return nil, fmt.Errorf("bind: unknown opt: %s", key)
+		}
+	}
+	if volumeOpts.path == "" {
+		return nil, fmt.Errorf("bind: the %s opt is required", OptPath)
+	}
+	return volumeOpts, nil
+}
+
+func bindMount(path string, mountpoint string, readOnly bool) error {
+	if err := internal.Mount(path, mountpoint, "", internal.MountBind, ""); err != nil {
+		return err
+	}
+	if !readOnly {
+		return nil
+	}
+	// the read-only flag of a bind mount is only applied by a remount
+	if err := internal.Mount("", mountpoint, "", internal.MountBind|internal.MountRemount|internal.MountReadOnly, ""); err != nil {
+		_ = internal.Unmount(mountpoint)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+/*
+Package internal contains the code shared by the volume drivers in go.pedge.io/dockervolume/driver.
+*/
+package internal // import "go.pedge.io/dockervolume/driver/internal"
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// MountFlags are flags for Mount.
+type MountFlags uint
+
+const (
+	// MountReadOnly mounts read-only.
+	MountReadOnly MountFlags = 1 << iota
+	// MountNoDev does not allow device files.
+	MountNoDev
+	// MountNoSuid does not honor the set-user-ID and set-group-ID bits.
+	MountNoSuid
+	// MountBind makes a bind mount of the source.
+	MountBind
+	// MountRemount changes the flags of an existing mount.
+	MountRemount
+)
+
+var (
+	// ErrUnsupported is returned by Mount, Unmount and OpenDir on other platforms than linux.
+	ErrUnsupported = errors.New("dockervolume: mounting volumes is only supported on linux")
+)
+
+// CheckName errors if the name is not a single path element. The error is
+// prefixed with driverName.
+func CheckName(driverName string, name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\\x00") {
+		return fmt.Errorf("%s: invalid volume name: %q", driverName, name)
+	}
+	return nil
+}
+
+// Mount mounts source on target with the filesystem type fsType, the flags
+// and the filesystem specific data.
+func Mount(source string, target string, fsType string, flags MountFlags, data string) error {
+	return mount(source, target, fsType, flags, data)
+}
+
+// Unmount unmounts target. It is not an error if target is not mounted.
+func Unmount(target string) error {
+	return unmount(target)
+}
+
+// OpenDir opens the directory at path, and errors if the last element of path
+// is a symlink.
+//
+// The open directory stays the same if path is changed, and can be mounted
+// with its FilePath.
+func OpenDir(path string) (*os.File, error) {
+	return openDir(path)
+}
+
+// FilePath returns a path to the open file that refers to it and nothing
+// else. Readlink on the path returns the current path of the file.
+func FilePath(file *os.File) string {
+	return filePath(file)
+}
@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckName(t *testing.T) {
+	for _, name := range []string{"foo", "foo.bar", "foo-bar_1", "..foo"} {
+		require.NoError(t, CheckName("test", name), "%q", name)
+	}
+	for _, name := range []string{"", ".", "..", "../foo", "foo/bar", "foo\\bar", "foo\x00"} {
+		require.Error(t, CheckName("test", name), "%q", name)
+	}
+	require.Equal(t, `test: invalid volume name: "foo/bar"`, CheckName("test", "foo/bar").Error())
+}
+
+func TestOpenDir(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("OpenDir is only supported on linux")
+	}
+	dirPath, err := ioutil.TempDir("", "internal")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dirPath) }()
+	dirPath, err = filepath.EvalSymlinks(dirPath)
+	require.NoError(t, err)
+	require.NoError(t, os.Mkdir(filepath.Join(dirPath, "dir"), 0755))
+	require.NoError(t, os.Symlink(filepath.Join(dirPath, "dir"), filepath.Join(dirPath, "symlink")))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "file"), nil, 0644))
+
+	dir, err := OpenDir(filepath.Join(dirPath, "dir"))
+	require.NoError(t, err)
+	defer func() { _ = dir.Close() }()
+	// the open directory is followed when its path changes
+	require.NoError(t, os.Rename(filepath.Join(dirPath, "dir"), filepath.Join(dirPath, "moved")))
+	require.NoError(t, os.Symlink(filepath.Join(dirPath, "symlink"), filepath.Join(dirPath, "dir")))
+	path, err := os.Readlink(FilePath(dir))
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dirPath, "moved"), path)
+	for _, name := range []string{"symlink", "file", "missing"} {
+		_, err := OpenDir(filepath.Join(dirPath, name))
+		require.Error(t, err, name)
+	}
+}
@@ -0,0 +1,46 @@
+//go:build linux
+// +build linux
+
+package internal
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+var (
+	mountFlagsToSyscallFlags = map[MountFlags]uintptr{
+		MountReadOnly: syscall.MS_RDONLY,
+		MountNoDev:    syscall.MS_NODEV,
+		MountNoSuid:   syscall.MS_NOSUID,
+		MountBind:     syscall.MS_BIND,
+		MountRemount:  syscall.MS_REMOUNT,
+	}
+)
+
+func mount(source string, target string, fsType string, flags MountFlags, data string) error {
+	var syscallFlags uintptr
+	for mountFlag, syscallFlag := range mountFlagsToSyscallFlags {
+		if flags&mountFlag != 0 {
+			syscallFlags |= syscallFlag
+		}
+	}
+	return syscall.Mount(source, target, fsType, syscallFlags, data)
+}
+
+func unmount(target string) error {
+	// EINVAL is returned if target is not a mountpoint
+	if err := syscall.Unmount(target, 0); err != nil && err != syscall.EINVAL {
+		return err
+	}
+	return nil
+}
+
+func openDir(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_RDONLY|syscall.O_DIRECTORY|syscall.O_NOFOLLOW, 0)
+}
+
+func filePath(file *os.File) string {
+	return fmt.Sprintf("/proc/self/fd/%d", file.Fd())
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+// +build !linux
+
+package internal
+
+import (
+	"os"
+)
+
+func mount(source string, target string, fsType string, flags MountFlags, data string) error {
+	return ErrUnsupported
+}
+
+func unmount(target string) error {
+	return ErrUnsupported
+}
+
+func openDir(path string) (*os.File, error) {
+	return nil, ErrUnsupported
+}
+
+func filePath(file *os.File) string {
+	return ""
+}
@@ -0,0 +1,51 @@
+/*
+Package localdir implements a dockervolume.VolumeDriver that keeps each volume in a directory on the local host.
+
+The directory of a volume is created on Create, kept across mounts, and
+only deleted on Remove. Mount and Unmount do not mount anything, the
+mountpoint is the directory itself.
+
+The opts of a volume are:
+
+	uid      the user id of the owner of the directory, by default the user of the process
+	gid      the group id of the owner of the directory, by default the group of the process
+	mode     the permissions of the directory in octal, by default 0755
+	subpath  a relative path within the directory of the volume to use as the mountpoint
+
+Any other opt is an error.
+*/
+package localdir // import "go.pedge.io/dockervolume/driver/localdir"
+
+import (
+	"os"
+
+	"go.pedge.io/dockervolume"
+)
+
+const (
+	// OptUID is the opt for the user id of the owner of the directory.
+	OptUID = "uid"
+	// OptGID is the opt for the group id of the owner of the directory.
+	OptGID = "gid"
+	// OptMode is the opt for the permissions of the directory, in octal.
+	OptMode = "mode"
+	// OptSubpath is the opt for the relative path within the directory of the volume to use as the mountpoint.
+	OptSubpath = "subpath"
+
+	// DefaultMode is the default permissions of the directory of a volume.
+	DefaultMode os.FileMode = 0755
+)
+
+// VolumeDriver is a dockervolume.VolumeDriver that keeps each volume in a
+// directory. It also implements dockervolume.VolumeDriverLister.
+type VolumeDriver interface {
+	dockervolume.VolumeDriver
+	dockervolume.VolumeDriverLister
+}
+
+// NewVolumeDriver returns a new VolumeDriver that keeps each volume in a
+// directory named after the volume under baseDirPath. baseDirPath is created
+// if it does not exist.
+func NewVolumeDriver(baseDirPath string) (VolumeDriver, error) {
+	return newVolumeDriver(baseDirPath)
+}
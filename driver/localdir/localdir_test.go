@@ -0,0 +1,136 @@
+package localdir
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.pedge.io/dockervolume"
+	"go.pedge.io/dockervolume/dockervolumetest"
+)
+
+func TestSuite(t *testing.T) {
+	dockervolumetest.RunSuite(
+		t,
+		func(dirPath string) (dockervolume.VolumeDriver, error) {
+			return NewVolumeDriver(dirPath)
+		},
+		dockervolumetest.SuiteOptions{
+			Opts: map[string]string{OptMode: "0700", OptSubpath: "data"},
+		},
+	)
+}
+
+func TestDataIsKept(t *testing.T) {
+	volumeDriver, dirPath := newTestVolumeDriver(t)
+	defer func() { _ = os.RemoveAll(dirPath) }()
+	require.NoError(t, volumeDriver.Create("foo", nil))
+	mountpoint, err := volumeDriver.Mount("foo", nil)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dirPath, "foo"), mountpoint)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(mountpoint, "file"), []byte("data"), 0644))
+	require.NoError(t, volumeDriver.Unmount("foo", nil, mountpoint))
+	// creating the volume again keeps the data
+	require.NoError(t, volumeDriver.Create("foo", nil))
+	mountpoint, err = volumeDriver.Mount("foo", nil)
+	require.NoError(t, err)
+	data, err := ioutil.ReadFile(filepath.Join(mountpoint, "file"))
+	require.NoError(t, err)
+	require.Equal(t, "data", string(data))
+	require.NoError(t, volumeDriver.Unmount("foo", nil, mountpoint))
+	names, err := volumeDriver.ListVolumeNames()
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo"}, names)
+	require.NoError(t, volumeDriver.Remove("foo", nil, mountpoint))
+	_, err = os.Stat(filepath.Join(dirPath, "foo"))
+	require.True(t, os.IsNotExist(err))
+	_, err = volumeDriver.Mount("foo", nil)
+	require.Error(t, err)
+}
+
+func TestOpts(t *testing.T) {
+	volumeDriver, dirPath := newTestVolumeDriver(t)
+	defer func() { _ = os.RemoveAll(dirPath) }()
+	opts := map[string]string{OptMode: "0710", OptSubpath: "a/b"}
+	require.NoError(t, volumeDriver.Create("foo", opts))
+	mountpoint, err := volumeDriver.Mount("foo", opts)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dirPath, "foo", "a", "b"), mountpoint)
+	for _, path := range []string{"foo", "foo/a", "foo/a/b"} {
+		fileInfo, err := os.Stat(filepath.Join(dirPath, path))
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0710), fileInfo.Mode().Perm(), path)
+	}
+	for _, opts := range []map[string]string{
+		{OptMode: "0999"},
+		{OptMode: "17777"},
+		{OptUID: "-1"},
+		{OptGID: "root"},
+		{OptSubpath: "../bar"},
+		{OptSubpath: "/etc"},
+		{"size": "1G"},
+	} {
+		require.Error(t, volumeDriver.Create("bar", opts), "%v", opts)
+	}
+}
+
+func TestOwner(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("changing the owner of a directory requires root")
+	}
+	volumeDriver, dirPath := newTestVolumeDriver(t)
+	defer func() { _ = os.RemoveAll(dirPath) }()
+	opts := map[string]string{OptUID: "1234", OptGID: "5678"}
+	require.NoError(t, volumeDriver.Create("foo", opts))
+	fileInfo, err := os.Stat(filepath.Join(dirPath, "foo"))
+	require.NoError(t, err)
+	stat := fileInfo.Sys().(*syscall.Stat_t)
+	require.Equal(t, uint32(1234), stat.Uid)
+	require.Equal(t, uint32(5678), stat.Gid)
+}
+
+func TestPathTraversal(t *testing.T) {
+	volumeDriver, dirPath := newTestVolumeDriver(t)
+	defer func() { _ = os.RemoveAll(dirPath) }()
+	for _, name := range []string{"", ".", "..", "../foo", "foo/bar", "foo\\bar", "foo\x00"} {
+		require.Error(t, volumeDriver.Create(name, nil), "%q", name)
+		require.Error(t, volumeDriver.Remove(name, nil, ""), "%q", name)
+		_, err := volumeDriver.Mount(name, nil)
+		require.Error(t, err, "%q", name)
+	}
+	outsideDirPath, err := ioutil.TempDir("", "localdir")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(outsideDirPath) }()
+	// a container replaces the subpath with a symlink out of the volume
+	opts := map[string]string{OptSubpath: "data"}
+	require.NoError(t, volumeDriver.Create("foo", opts))
+	require.NoError(t, os.Remove(filepath.Join(dirPath, "foo", "data")))
+	require.NoError(t, os.Symlink(outsideDirPath, filepath.Join(dirPath, "foo", "data")))
+	_, err = volumeDriver.Mount("foo", opts)
+	require.Error(t, err)
+	require.Error(t, volumeDriver.Create("foo", opts))
+	// nothing is created through a symlink in the subpath
+	require.Error(t, volumeDriver.Create("foo", map[string]string{OptSubpath: "data/sub/dir"}))
+	fileInfos, err := ioutil.ReadDir(outsideDirPath)
+	require.NoError(t, err)
+	require.Empty(t, fileInfos)
+	// the directory of a volume is a symlink
+	require.NoError(t, os.Symlink(outsideDirPath, filepath.Join(dirPath, "bar")))
+	require.Error(t, volumeDriver.Create("bar", nil))
+	_, err = volumeDriver.Mount("bar", nil)
+	require.Error(t, err)
+}
+
+func newTestVolumeDriver(t *testing.T) (VolumeDriver, string) {
+	dirPath, err := ioutil.TempDir("", "localdir")
+	require.NoError(t, err)
+	volumeDriver, err := NewVolumeDriver(dirPath)
+	require.NoError(t, err)
+	// the driver works with the real path of the directory
+	dirPath, err = filepath.EvalSymlinks(dirPath)
+	require.NoError(t, err)
+	return volumeDriver, dirPath
+}
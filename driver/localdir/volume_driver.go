@@ -0,0 +1,228 @@
+package localdir
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go.pedge.io/dockervolume/driver/internal"
+	"go.pedge.io/pkg/map"
+)
+
+type volumeDriver struct {
+	baseDirPath string
+}
+
+func newVolumeDriver(baseDirPath string) (*volumeDriver, error) {
+	if err := os.MkdirAll(baseDirPath, 0755); err != nil {
+		return nil, err
+	}
+	baseDirPath, err := filepath.Abs(baseDirPath)
+	if err != nil {
+		return nil, err
+	}
+	// resolve symlinks once so that the checks against escapes compare real paths
+	baseDirPath, err = filepath.EvalSymlinks(baseDirPath)
+	if err != nil {
+		return nil, err
+	}
+	return &volumeDriver{baseDirPath}, nil
+}
+
+func (v *volumeDriver) Create(name string, opts pkgmap.StringStringMap) error {
+	dirPath, err := v.getDirPath(name)
+	if err != nil {
+		return err
+	}
+	volumeOpts, err := parseVolumeOpts(opts)
+	if err != nil {
+		return err
+	}
+	// an existing directory is kept, so that no data is lost if a volume is created again
+	if err := os.Mkdir(dirPath, volumeOpts.mode); err != nil && !os.IsExist(err) {
+		return err
+	}
+	if err := checkDir(dirPath); err != nil {
+		return err
+	}
+	// the directories from the directory of the volume to the mountpoint are
+	// checked before they are created, so that a symlink in the subpath can
+	// not make Create write outside of the volume
+	paths := []string{dirPath}
+	if volumeOpts.subpath != "." {
+		path := dirPath
+		for _, element := range strings.Split(volumeOpts.subpath, string(filepath.Separator)) {
+			path = filepath.Join(path, element)
+			if err := checkDir(path); err != nil {
+				if !os.IsNotExist(err) {
+					return err
+				}
+				if err := os.Mkdir(path, volumeOpts.mode); err != nil {
+					return err
+				}
+			}
+			paths = append(paths, path)
+		}
+	}
+	// every directory from the directory of the volume to the mountpoint gets the mode and owner
+	for _, path := range paths {
+		// mkdir is subject to the umask
+		if err := os.Chmod(path, volumeOpts.mode); err != nil {
+			return err
+		}
+		if volumeOpts.uid != -1 || volumeOpts.gid != -1 {
+			if err := os.Chown(path, volumeOpts.uid, volumeOpts.gid); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (v *volumeDriver) Remove(name string, _ pkgmap.StringStringMap, _ string) error {
+	dirPath, err := v.getDirPath(name)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dirPath)
+}
+
+func (v *volumeDriver) Mount(name string, opts pkgmap.StringStringMap) (string, error) {
+	dirPath, err := v.getDirPath(name)
+	if err != nil {
+		return "", err
+	}
+	volumeOpts, err := parseVolumeOpts(opts)
+	if err != nil {
+		return "", err
+	}
+	if err := checkDir(dirPath); err != nil {
+		return "", err
+	}
+	mountpoint := filepath.Join(dirPath, volumeOpts.subpath)
+	fileInfo, err := os.Stat(mountpoint)
+	if err != nil {
+		return "", err
+	}
+	if !fileInfo.IsDir() {
+		return "", fmt.Errorf("localdir: %s is not a directory", mountpoint)
+	}
+	// a container could have replaced the subpath with a symlink out of the volume
+	if err := checkWithin(dirPath, mountpoint); err != nil {
+		return "", err
+	}
+	return mountpoint, nil
+}
+
+func (v *volumeDriver) Unmount(_ string, _ pkgmap.StringStringMap, _ string) error {
+	return nil
+}
+
+func (v *volumeDriver) ListVolumeNames() ([]string, error) {
+	fileInfos, err := ioutil.ReadDir(v.baseDirPath)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, fileInfo := range fileInfos {
+		if fileInfo.IsDir() {
+			names = append(names, fileInfo.Name())
+		}
+	}
+	return names, nil
+}
+
+// getDirPath returns the directory of the volume with the given name, and
+// errors if the name is not a single path element.
+func (v *volumeDriver) getDirPath(name string) (string, error) {
+	if err := internal.CheckName("localdir", name); err != nil {
+		return "", err
+	}
+	return filepath.Join(v.baseDirPath, name), nil
+}
+
+type volumeOpts struct {
+	uid     int
+	gid     int
+	mode    os.FileMode
+	subpath string
+}
+
+func parseVolumeOpts(opts pkgmap.StringStringMap) (*volumeOpts, error) {
+	volumeOpts := &volumeOpts{
+		uid:     -1,
+		gid:     -1,
+		mode:    DefaultMode,
+		subpath: ".",
+	}
+	for key, value := range opts {
+		switch key {
+		case OptUID:
+			uid, err := parseID(key, value)
+			if err != nil {
+				return nil, err
+			}
+			volumeOpts.uid = uid
+		case OptGID:
+			gid, err := parseID(key, value)
+			if err != nil {
+				return nil, err
+			}
+			volumeOpts.gid = gid
+		case OptMode:
+			mode, err := strconv.ParseUint(value, 8, 32)
+			if err != nil || mode > 07777 {
+				return nil, fmt.Errorf("localdir: invalid %s, must be octal permissions: %s", key, value)
+			}
+			volumeOpts.mode = os.FileMode(mode)
+		case OptSubpath:
+			subpath := filepath.Clean(value)
+			if filepath.IsAbs(subpath) || subpath == ".." || strings.HasPrefix(subpath, "../") {
+				return nil, fmt.Errorf("localdir: invalid %s, must be a relative path within the volume: %s", key, value)
+			}
+			volumeOpts.subpath = subpath
+		default:
+			return nil, fmt.Errorf("localdir: unknown opt: %s", key)
+		}
+	}
+	return volumeOpts, nil
+}
+
+func parseID(key string, value string) (int, error) {
+	id, err := strconv.ParseUint(value, 10, 31)
+	if err != nil {
+		return 0, fmt.Errorf("localdir: invalid %s: %s", key, value)
+	}
+	return int(id), nil
+}
+
+// checkDir errors if dirPath is not a directory, in particular if it is a symlink.
+func checkDir(dirPath string) error {
+	fileInfo, err := os.Lstat(dirPath)
+	if err != nil {
+		return err
+	}
+	if !fileInfo.IsDir() {
+		return fmt.Errorf("localdir: %s is not a directory", dirPath)
+	}
+	return nil
+}
+
+// checkWithin errors if path is not dirPath or within dirPath once symlinks are resolved.
+func checkWithin(dirPath string, path string) error {
+	realDirPath, err := filepath.EvalSymlinks(dirPath)
+	if err != nil {
+		return err
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	if realPath != realDirPath && !strings.HasPrefix(realPath, realDirPath+string(filepath.Separator)) {
+		return fmt.Errorf("localdir: %s is outside of the volume", path)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+/*
+Package loopback implements a dockervolume.VolumeDriver that keeps each volume in a filesystem image file, for hard per-volume size limits.
+
+On Create, a sparse image file of the requested size is allocated and
+formatted with mkfs. On Mount, the image file is attached to a loop device
+with losetup and mounted. On Unmount, it is unmounted and detached. On
+Remove, the image file is deleted.
+
+The opts of a volume are:
+
+	size  the size of the filesystem, in bytes or with a K, M, G or T suffix for powers of 1024, required
+	fs    the type of the filesystem, ext4 or xfs, by default ext4
+
+Any other opt is an error. Mounting volumes is only supported on Linux, and
+requires root and the losetup and mkfs.* commands.
+*/
+package loopback // import "go.pedge.io/dockervolume/driver/loopback"
+
+import (
+	"go.pedge.io/dockervolume"
+)
+
+const (
+	// OptSize is the opt for the size of the filesystem.
+	OptSize = "size"
+	// OptFS is the opt for the type of the filesystem.
+	OptFS = "fs"
+
+	// FSExt4 is the ext4 filesystem type.
+	FSExt4 = "ext4"
+	// FSXFS is the xfs filesystem type.
+	FSXFS = "xfs"
+	// DefaultFS is the default filesystem type.
+	DefaultFS = FSExt4
+)
+
+// VolumeDriver is a dockervolume.VolumeDriver that keeps each volume in a
+// filesystem image file. It also implements dockervolume.VolumeDriverLister.
+type VolumeDriver interface {
+	dockervolume.VolumeDriver
+	dockervolume.VolumeDriverLister
+}
+
+// NewVolumeDriver returns a new VolumeDriver that keeps the image files of
+// the volumes in baseDirPath/images and mounts them in baseDirPath/mounts.
+// The directories are created if they do not exist.
+func NewVolumeDriver(baseDirPath string) (VolumeDriver, error) {
+	return newVolumeDriver(baseDirPath)
+}
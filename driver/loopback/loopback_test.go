@@ -0,0 +1,123 @@
+package loopback
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.pedge.io/dockervolume"
+	"go.pedge.io/dockervolume/dockervolumetest"
+)
+
+func TestSuite(t *testing.T) {
+	requireLoopDevices(t)
+	dockervolumetest.RunSuite(
+		t,
+		func(dirPath string) (dockervolume.VolumeDriver, error) {
+			return NewVolumeDriver(dirPath)
+		},
+		dockervolumetest.SuiteOptions{
+			Opts:          map[string]string{OptSize: "16M"},
+			RequiresOpts:  true,
+			NumConcurrent: 4,
+		},
+	)
+}
+
+func TestSizeLimit(t *testing.T) {
+	requireLoopDevices(t)
+	volumeDriver, dirPath := newTestVolumeDriver(t)
+	defer func() { _ = os.RemoveAll(dirPath) }()
+	opts := map[string]string{OptSize: "16M"}
+	require.NoError(t, volumeDriver.Create("foo", opts))
+	imagePath := filepath.Join(dirPath, "images", "foo.img")
+	fileInfo, err := os.Stat(imagePath)
+	require.NoError(t, err)
+	require.Equal(t, int64(16<<20), fileInfo.Size())
+	require.Error(t, volumeDriver.Create("foo", opts))
+	mountpoint, err := volumeDriver.Mount("foo", opts)
+	require.NoError(t, err)
+	require.Error(t, ioutil.WriteFile(filepath.Join(mountpoint, "big"), make([]byte, 32<<20), 0644))
+	require.NoError(t, os.Remove(filepath.Join(mountpoint, "big")))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(mountpoint, "small"), []byte("data"), 0644))
+	require.NoError(t, volumeDriver.Unmount("foo", opts, mountpoint))
+	_, err = os.Stat(filepath.Join(mountpoint, "small"))
+	require.True(t, os.IsNotExist(err))
+	// the data is kept in the image file across mounts
+	mountpoint, err = volumeDriver.Mount("foo", opts)
+	require.NoError(t, err)
+	data, err := ioutil.ReadFile(filepath.Join(mountpoint, "small"))
+	require.NoError(t, err)
+	require.Equal(t, "data", string(data))
+	require.NoError(t, volumeDriver.Unmount("foo", opts, mountpoint))
+	names, err := volumeDriver.ListVolumeNames()
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo"}, names)
+	require.NoError(t, volumeDriver.Remove("foo", opts, mountpoint))
+	_, err = os.Stat(imagePath)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestOpts(t *testing.T) {
+	volumeDriver, dirPath := newTestVolumeDriver(t)
+	defer func() { _ = os.RemoveAll(dirPath) }()
+	for _, opts := range []map[string]string{
+		nil,
+		{OptSize: "0"},
+		{OptSize: "-1G"},
+		{OptSize: "1P"},
+		{OptSize: "99999999999T"},
+		{OptSize: "1000000"},
+		{OptSize: "1000"},
+		{OptSize: "1G", OptFS: "btrfs"},
+		{OptSize: "1G", "mode": "0755"},
+	} {
+		require.Error(t, volumeDriver.Create("foo", opts), "%v", opts)
+	}
+	for _, name := range []string{"", "..", "foo/bar"} {
+		require.Error(t, volumeDriver.Create(name, map[string]string{OptSize: "16M"}), "%q", name)
+	}
+	for value, expected := range map[string]uint64{
+		"1024": 1024,
+		"512":  512,
+		"1k":   1 << 10,
+		"16M":  16 << 20,
+		"2G":   2 << 30,
+		"1T":   1 << 40,
+	} {
+		size, err := parseSize(value)
+		require.NoError(t, err)
+		require.Equal(t, expected, size)
+	}
+}
+
+func TestRun(t *testing.T) {
+	// warnings on the standard error, as losetup prints them, are not in the output
+	output, err := run("sh", "-c", "echo warning >&2; echo /dev/loop0")
+	require.NoError(t, err)
+	require.Equal(t, "/dev/loop0\n", output)
+	_, err = run("sh", "-c", "echo failed >&2; exit 1")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed")
+}
+
+func newTestVolumeDriver(t *testing.T) (VolumeDriver, string) {
+	dirPath, err := ioutil.TempDir("", "loopback")
+	require.NoError(t, err)
+	volumeDriver, err := NewVolumeDriver(dirPath)
+	require.NoError(t, err)
+	return volumeDriver, dirPath
+}
+
+// requireLoopDevices skips the test if loop devices can not be used.
+func requireLoopDevices(t *testing.T) {
+	dockervolumetest.RequireRoot(t)
+	for _, command := range []string{"losetup", "mkfs.ext4"} {
+		if _, err := exec.LookPath(command); err != nil {
+			t.Skipf("%s is not installed", command)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package loopback
+
+import (
+	"strings"
+
+	"go.pedge.io/dockervolume/driver/internal"
+)
+
+func mountImage(imagePath string, mountpoint string, fs string) error {
+	output, err := run("losetup", "--find", "--show", imagePath)
+	if err != nil {
+		return err
+	}
+	device := strings.TrimSpace(output)
+	// the image is created by the user of the volume, so device files and
+	// set-user-ID binaries in it are not honored
+	if err := internal.Mount(device, mountpoint, fs, internal.MountNoDev|internal.MountNoSuid, ""); err != nil {
+		_, _ = run("losetup", "--detach", device)
+		return err
+	}
+	return nil
+}
+
+func unmountImage(imagePath string, mountpoint string) error {
+	if err := internal.Unmount(mountpoint); err != nil {
+		return err
+	}
+	devices, err := getDevices(imagePath)
+	if err != nil {
+		return err
+	}
+	for _, device := range devices {
+		if _, err := run("losetup", "--detach", device); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getDevices returns the loop devices the image file is attached to.
+func getDevices(imagePath string) ([]string, error) {
+	output, err := run("losetup", "--associated", imagePath)
+	if err != nil {
+		return nil, err
+	}
+	var devices []string
+	for _, line := range strings.Split(output, "\n") {
+		// each line is "/dev/loopN: [device]:inode (imagePath)"
+		if i := strings.Index(line, ":"); i > 0 {
+			devices = append(devices, line[:i])
+		}
+	}
+	return devices, nil
+}
@@ -0,0 +1,213 @@
+package loopback
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go.pedge.io/dockervolume/driver/internal"
+	"go.pedge.io/pkg/map"
+)
+
+const imageExt = ".img"
+
+const (
+	// sectorSize is the size of the sectors of a loop device.
+	sectorSize = 512
+)
+
+var (
+	fsToMkfsArgs = map[string][]string{
+		FSExt4: {"mkfs.ext4", "-F", "-q"},
+		FSXFS:  {"mkfs.xfs", "-f", "-q"},
+	}
+	sizeSuffixToMultiplier = map[string]uint64{
+		"K": 1 << 10,
+		"M": 1 << 20,
+		"G": 1 << 30,
+		"T": 1 << 40,
+	}
+)
+
+type volumeDriver struct {
+	imagesDirPath string
+	mountsDirPath string
+}
+
+func newVolumeDriver(baseDirPath string) (*volumeDriver, error) {
+	imagesDirPath := filepath.Join(baseDirPath, "images")
+	mountsDirPath := filepath.Join(baseDirPath, "mounts")
+	for _, dirPath := range []string{imagesDirPath, mountsDirPath} {
+		if err := os.MkdirAll(dirPath, 0700); err != nil {
+			return nil, err
+		}
+	}
+	return &volumeDriver{
+		imagesDirPath,
+		mountsDirPath,
+	}, nil
+}
+
+func (v *volumeDriver) Create(name string, opts pkgmap.StringStringMap) (retErr error) {
+	if err := internal.CheckName("loopback", name); err != nil {
+		return err
+	}
+	volumeOpts, err := parseVolumeOpts(opts)
+	if err != nil {
+		return err
+	}
+	if volumeOpts.sizeBytes == 0 {
+		return fmt.Errorf("loopback: the %s opt is required", OptSize)
+	}
+	imagePath := v.getImagePath(name)
+	// O_EXCL so that an existing image, and its data, is never formatted again
+	file, err := os.OpenFile(imagePath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if retErr != nil {
+			_ = os.Remove(imagePath)
+		}
+	}()
+	// truncate makes a sparse file, the space is only used when written
+	if err := file.Truncate(int64(volumeOpts.sizeBytes)); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	mkfsArgs := fsToMkfsArgs[volumeOpts.fs]
+	_, err = run(mkfsArgs[0], append(mkfsArgs[1:], imagePath)...)
+	return err
+}
+
+func (v *volumeDriver) Remove(name string, _ pkgmap.StringStringMap, _ string) error {
+	if err := internal.CheckName("loopback", name); err != nil {
+		return err
+	}
+	if err := os.Remove(v.getMountpoint(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Remove(v.getImagePath(name))
+}
+
+func (v *volumeDriver) Mount(name string, opts pkgmap.StringStringMap) (string, error) {
+	if err := internal.CheckName("loopback", name); err != nil {
+		return "", err
+	}
+	volumeOpts, err := parseVolumeOpts(opts)
+	if err != nil {
+		return "", err
+	}
+	imagePath := v.getImagePath(name)
+	if _, err := os.Stat(imagePath); err != nil {
+		return "", err
+	}
+	mountpoint := v.getMountpoint(name)
+	if err := os.MkdirAll(mountpoint, 0755); err != nil {
+		return "", err
+	}
+	if err := mountImage(imagePath, mountpoint, volumeOpts.fs); err != nil {
+		return "", err
+	}
+	return mountpoint, nil
+}
+
+func (v *volumeDriver) Unmount(name string, _ pkgmap.StringStringMap, mountpoint string) error {
+	if err := internal.CheckName("loopback", name); err != nil {
+		return err
+	}
+	return unmountImage(v.getImagePath(name), mountpoint)
+}
+
+func (v *volumeDriver) ListVolumeNames() ([]string, error) {
+	fileInfos, err := ioutil.ReadDir(v.imagesDirPath)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, fileInfo := range fileInfos {
+		if fileInfo.Mode().IsRegular() && strings.HasSuffix(fileInfo.Name(), imageExt) {
+			names = append(names, strings.TrimSuffix(fileInfo.Name(), imageExt))
+		}
+	}
+	return names, nil
+}
+
+func (v *volumeDriver) getImagePath(name string) string {
+	return filepath.Join(v.imagesDirPath, name+imageExt)
+}
+
+func (v *volumeDriver) getMountpoint(name string) string {
+	return filepath.Join(v.mountsDirPath, name)
+}
+
+type volumeOpts struct {
+	sizeBytes uint64
+	fs        string
+}
+
+func parseVolumeOpts(opts pkgmap.StringStringMap) (*volumeOpts, error) {
+	volumeOpts := &volumeOpts{
+		fs: DefaultFS,
+	}
+	for key, value := range opts {
+		switch key {
+		case OptSize:
+			sizeBytes, err := parseSize(value)
+			if err != nil {
+				return nil, err
+			}
+			volumeOpts.sizeBytes = sizeBytes
+		case OptFS:
+			if _, ok := fsToMkfsArgs[value]; !ok {
+				return nil, fmt.Errorf("loopback: invalid %s, must be %s or %s: %s", key, FSExt4, FSXFS, value)
+			}
+			volumeOpts.fs = value
+		default:
+			return nil, fmt.Errorf("loopback: unknown opt: %s", key)
+		}
+	}
+	return volumeOpts, nil
+}
+
+// parseSize parses a size in bytes, or with a K, M, G or T suffix for powers of 1024.
+// The size must be a multiple of the 512 byte sectors of a loop device.
+func parseSize(value string) (uint64, error) {
+	multiplier := uint64(1)
+	number := value
+	if len(value) > 0 {
+		if m, ok := sizeSuffixToMultiplier[strings.ToUpper(value[len(value)-1:])]; ok {
+			multiplier = m
+			number = value[:len(value)-1]
+		}
+	}
+	size, err := strconv.ParseUint(number, 10, 64)
+	if err != nil || size == 0 || size > (1<<63-1)/multiplier {
+		return 0, fmt.Errorf("loopback: invalid %s: %s", OptSize, value)
+	}
+	if size*multiplier%sectorSize != 0 {
+		return 0, fmt.Errorf("loopback: invalid %s, must be a multiple of %d bytes: %s", OptSize, sectorSize, value)
+	}
+	return size * multiplier, nil
+}
+
+// run runs a command and returns its standard output, with the standard error in the error if it fails.
+// Warnings on the standard error are not part of the output.
+func run(name string, args ...string) (string, error) {
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("loopback: %s %s: %s: %s", name, strings.Join(args, " "), err.Error(), strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
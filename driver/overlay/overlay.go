@@ -0,0 +1,51 @@
+/*
+Package overlay implements a dockervolume.VolumeDriver for copy-on-write volumes with overlayfs.
+
+A volume starts as a copy of a read-only seed directory or of another
+volume, without duplicating it. On Mount, an overlayfs is assembled with the
+seed as the lower layer and an upper and a work directory of the volume, so
+every change is written to the volume only. Many volumes can share a seed.
+
+The opts of a volume are:
+
+	lower  the name of a seed directory in the seed directory of the VolumeDriver,
+	       or volume:name for the contents of the volume with the given name, required
+
+Any other opt is an error. A volume used as the lower layer of another
+volume cannot be removed before it, and should not be changed while the other
+volume is mounted, as overlayfs does not support changes to lower layers.
+Mounting volumes is only supported on Linux, and requires root.
+*/
+package overlay // import "go.pedge.io/dockervolume/driver/overlay"
+
+import (
+	"go.pedge.io/dockervolume"
+)
+
+const (
+	// OptLower is the opt for the lower layer of a volume.
+	OptLower = "lower"
+	// LowerVolumePrefix is the prefix of a lower layer that is another volume.
+	LowerVolumePrefix = "volume:"
+)
+
+// VolumeDriver is a dockervolume.VolumeDriver for copy-on-write volumes.
+// It also implements dockervolume.VolumeDriverLister.
+type VolumeDriver interface {
+	dockervolume.VolumeDriver
+	dockervolume.VolumeDriverLister
+}
+
+// VolumeDriverOptions are options for a VolumeDriver.
+type VolumeDriverOptions struct {
+	// SeedDirPath is the directory of the seed directories. Volumes can only
+	// use the seed directories in it as lower layers. If not set, volumes can
+	// only use other volumes as lower layers.
+	SeedDirPath string
+}
+
+// NewVolumeDriver returns a new VolumeDriver that keeps the directories of
+// the volumes under baseDirPath. baseDirPath is created if it does not exist.
+func NewVolumeDriver(baseDirPath string, opts VolumeDriverOptions) (VolumeDriver, error) {
+	return newVolumeDriver(baseDirPath, opts)
+}
@@ -0,0 +1,131 @@
+package overlay
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.pedge.io/dockervolume"
+	"go.pedge.io/dockervolume/dockervolumetest"
+)
+
+func TestSuite(t *testing.T) {
+	dockervolumetest.RequireRoot(t)
+	seedDirPath := newTestSeedDir(t)
+	defer func() { _ = os.RemoveAll(seedDirPath) }()
+	dockervolumetest.RunSuite(
+		t,
+		func(dirPath string) (dockervolume.VolumeDriver, error) {
+			return NewVolumeDriver(dirPath, VolumeDriverOptions{SeedDirPath: seedDirPath})
+		},
+		dockervolumetest.SuiteOptions{
+			Opts:         map[string]string{OptLower: "seed"},
+			RequiresOpts: true,
+		},
+	)
+}
+
+func TestCopyOnWrite(t *testing.T) {
+	dockervolumetest.RequireRoot(t)
+	seedDirPath := newTestSeedDir(t)
+	defer func() { _ = os.RemoveAll(seedDirPath) }()
+	volumeDriver, dirPath := newTestVolumeDriver(t, seedDirPath)
+	defer func() { _ = os.RemoveAll(dirPath) }()
+
+	opts := map[string]string{OptLower: "seed"}
+	require.NoError(t, volumeDriver.Create("foo", opts))
+	require.NoError(t, volumeDriver.Create("bar", opts))
+	fooMountpoint, err := volumeDriver.Mount("foo", opts)
+	require.NoError(t, err)
+	barMountpoint, err := volumeDriver.Mount("bar", opts)
+	require.NoError(t, err)
+	requireFileEquals(t, filepath.Join(fooMountpoint, "file"), "seed")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(fooMountpoint, "file"), []byte("foo"), 0644))
+	requireFileEquals(t, filepath.Join(fooMountpoint, "file"), "foo")
+	// neither the seed nor the other volumes see the change
+	requireFileEquals(t, filepath.Join(barMountpoint, "file"), "seed")
+	requireFileEquals(t, filepath.Join(seedDirPath, "seed", "file"), "seed")
+	require.NoError(t, volumeDriver.Unmount("foo", opts, fooMountpoint))
+	require.NoError(t, volumeDriver.Unmount("bar", opts, barMountpoint))
+
+	// a volume can start as a copy of another volume
+	bazOpts := map[string]string{OptLower: LowerVolumePrefix + "foo"}
+	require.NoError(t, volumeDriver.Create("baz", bazOpts))
+	bazMountpoint, err := volumeDriver.Mount("baz", bazOpts)
+	require.NoError(t, err)
+	requireFileEquals(t, filepath.Join(bazMountpoint, "file"), "foo")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(bazMountpoint, "other"), []byte("baz"), 0644))
+	require.NoError(t, volumeDriver.Unmount("baz", bazOpts, bazMountpoint))
+	// the changes are kept across mounts
+	bazMountpoint, err = volumeDriver.Mount("baz", bazOpts)
+	require.NoError(t, err)
+	requireFileEquals(t, filepath.Join(bazMountpoint, "other"), "baz")
+	require.NoError(t, volumeDriver.Unmount("baz", bazOpts, bazMountpoint))
+
+	require.Error(t, volumeDriver.Remove("foo", opts, ""))
+	require.NoError(t, volumeDriver.Remove("baz", bazOpts, ""))
+	require.NoError(t, volumeDriver.Remove("foo", opts, ""))
+	names, err := volumeDriver.ListVolumeNames()
+	require.NoError(t, err)
+	require.Equal(t, []string{"bar"}, names)
+}
+
+func TestOpts(t *testing.T) {
+	seedDirPath := newTestSeedDir(t)
+	defer func() { _ = os.RemoveAll(seedDirPath) }()
+	outsideDirPath, err := ioutil.TempDir("", "overlay")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(outsideDirPath) }()
+	require.NoError(t, os.Symlink(outsideDirPath, filepath.Join(seedDirPath, "escape")))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(seedDirPath, "file"), nil, 0644))
+	volumeDriver, dirPath := newTestVolumeDriver(t, seedDirPath)
+	defer func() { _ = os.RemoveAll(dirPath) }()
+
+	for _, opts := range []map[string]string{
+		nil,
+		{OptLower: "missing"},
+		{OptLower: "escape"},
+		{OptLower: "file"},
+		{OptLower: "../seed"},
+		{OptLower: ".."},
+		{OptLower: LowerVolumePrefix + "missing"},
+		{OptLower: LowerVolumePrefix + "../foo"},
+		{OptLower: "seed", "size": "1G"},
+	} {
+		require.Error(t, volumeDriver.Create("foo", opts), "%v", opts)
+	}
+	for _, name := range []string{"", "..", "foo/bar", "foo:bar", "foo,bar"} {
+		require.Error(t, volumeDriver.Create(name, map[string]string{OptLower: "seed"}), "%q", name)
+	}
+	require.NoError(t, volumeDriver.Create("foo", map[string]string{OptLower: "seed"}))
+	require.Error(t, volumeDriver.Create("foo", map[string]string{OptLower: "seed"}))
+
+	// without a seed directory, only volumes can be lower layers
+	volumeDriver, otherDirPath := newTestVolumeDriver(t, "")
+	defer func() { _ = os.RemoveAll(otherDirPath) }()
+	require.Error(t, volumeDriver.Create("foo", map[string]string{OptLower: "seed"}))
+}
+
+func newTestSeedDir(t *testing.T) string {
+	seedDirPath, err := ioutil.TempDir("", "overlay")
+	require.NoError(t, err)
+	require.NoError(t, os.Mkdir(filepath.Join(seedDirPath, "seed"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(seedDirPath, "seed", "file"), []byte("seed"), 0644))
+	return seedDirPath
+}
+
+func newTestVolumeDriver(t *testing.T, seedDirPath string) (VolumeDriver, string) {
+	dirPath, err := ioutil.TempDir("", "overlay")
+	require.NoError(t, err)
+	volumeDriver, err := NewVolumeDriver(dirPath, VolumeDriverOptions{SeedDirPath: seedDirPath})
+	require.NoError(t, err)
+	return volumeDriver, dirPath
+}
+
+func requireFileEquals(t *testing.T, path string, expected string) {
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, expected, string(data))
+}
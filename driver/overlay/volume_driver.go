@@ -0,0 +1,275 @@
+package overlay
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go.pedge.io/dockervolume/driver/internal"
+	"go.pedge.io/pkg/map"
+)
+
+const (
+	upperDirName  = "upper"
+	workDirName   = "work"
+	mergedDirName = "merged"
+	// lowersFileName is the file with the lower directories of a volume, one per line, topmost first.
+	lowersFileName = "lowers"
+	// invalidPathChars cannot be in the paths given to overlayfs in the mount options.
+	invalidPathChars = ":,\n"
+)
+
+type volumeDriver struct {
+	volumesDirPath string
+	seedDirPath    string
+	// lock is held by Create and Remove, so that a volume is not removed
+	// while a volume using it as lower layer is created.
+	lock *sync.Mutex
+}
+
+func newVolumeDriver(baseDirPath string, opts VolumeDriverOptions) (*volumeDriver, error) {
+	volumesDirPath, err := filepath.Abs(filepath.Join(baseDirPath, "volumes"))
+	if err != nil {
+		return nil, err
+	}
+	if strings.ContainsAny(volumesDirPath, invalidPathChars) {
+		return nil, fmt.Errorf("overlay: base directory cannot contain any of %q: %s", invalidPathChars, baseDirPath)
+	}
+	if err := os.MkdirAll(volumesDirPath, 0700); err != nil {
+		return nil, err
+	}
+	seedDirPath := opts.SeedDirPath
+	if seedDirPath != "" {
+		// compare real paths, as the seed directories are resolved with their symlinks
+		seedDirPath, err = filepath.EvalSymlinks(seedDirPath)
+		if err != nil {
+			return nil, err
+		}
+		if seedDirPath, err = filepath.Abs(seedDirPath); err != nil {
+			return nil, err
+		}
+	}
+	return &volumeDriver{
+		volumesDirPath,
+		seedDirPath,
+		&sync.Mutex{},
+	}, nil
+}
+
+func (v *volumeDriver) Create(name string, opts pkgmap.StringStringMap) (retErr error) {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	lower, err := parseLower(opts)
+	if err != nil {
+		return err
+	}
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	lowers, err := v.resolveLower(lower)
+	if err != nil {
+		return err
+	}
+	dirPath := v.getDirPath(name)
+	if err := os.Mkdir(dirPath, 0700); err != nil {
+		return err
+	}
+	defer func() {
+		if retErr != nil {
+			_ = os.RemoveAll(dirPath)
+		}
+	}()
+	for _, subDirName := range []string{upperDirName, workDirName, mergedDirName} {
+		if err := os.Mkdir(filepath.Join(dirPath, subDirName), 0755); err != nil {
+			return err
+		}
+	}
+	return ioutil.WriteFile(filepath.Join(dirPath, lowersFileName), []byte(strings.Join(lowers, "\n")+"\n"), 0600)
+}
+
+func (v *volumeDriver) Remove(name string, _ pkgmap.StringStringMap, _ string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	dependents, err := v.getDependents(name)
+	if err != nil {
+		return err
+	}
+	if len(dependents) > 0 {
+		return fmt.Errorf("overlay: %s is the lower layer of %s", name, strings.Join(dependents, ", "))
+	}
+	return os.RemoveAll(v.getDirPath(name))
+}
+
+func (v *volumeDriver) Mount(name string, _ pkgmap.StringStringMap) (string, error) {
+	if err := checkName(name); err != nil {
+		return "", err
+	}
+	dirPath := v.getDirPath(name)
+	lowers, err := readLowers(dirPath)
+	if err != nil {
+		return "", err
+	}
+	for _, lower := range lowers {
+		if _, err := os.Stat(lower); err != nil {
+			return "", err
+		}
+	}
+	mountpoint := filepath.Join(dirPath, mergedDirName)
+	if err := internal.Mount(
+		"overlay",
+		mountpoint,
+		"overlay",
+		0,
+		fmt.Sprintf(
+			"lowerdir=%s,upperdir=%s,workdir=%s",
+			strings.Join(lowers, ":"),
+			filepath.Join(dirPath, upperDirName),
+			filepath.Join(dirPath, workDirName),
+		),
+	); err != nil {
+		return "", err
+	}
+	return mountpoint, nil
+}
+
+func (v *volumeDriver) Unmount(name string, _ pkgmap.StringStringMap, mountpoint string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	return internal.Unmount(mountpoint)
+}
+
+func (v *volumeDriver) ListVolumeNames() ([]string, error) {
+	fileInfos, err := ioutil.ReadDir(v.volumesDirPath)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, fileInfo := range fileInfos {
+		if fileInfo.IsDir() {
+			names = append(names, fileInfo.Name())
+		}
+	}
+	return names, nil
+}
+
+// resolveLower returns the lower directories for the lower opt, topmost first.
+func (v *volumeDriver) resolveLower(lower string) ([]string, error) {
+	if strings.HasPrefix(lower, LowerVolumePrefix) {
+		lowerName := strings.TrimPrefix(lower, LowerVolumePrefix)
+		if err := checkName(lowerName); err != nil {
+			return nil, err
+		}
+		// the contents of a volume are its upper directory on top of its lower directories
+		lowerDirPath := v.getDirPath(lowerName)
+		lowers, err := readLowers(lowerDirPath)
+		if err != nil {
+			return nil, fmt.Errorf("overlay: lower volume %s: %s", lowerName, err.Error())
+		}
+		return append([]string{filepath.Join(lowerDirPath, upperDirName)}, lowers...), nil
+	}
+	if v.seedDirPath == "" {
+		return nil, fmt.Errorf("overlay: no seed directory, %s must be %sname", OptLower, LowerVolumePrefix)
+	}
+	if err := checkName(lower); err != nil {
+		return nil, err
+	}
+	seedPath, err := filepath.EvalSymlinks(filepath.Join(v.seedDirPath, lower))
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(seedPath, v.seedDirPath+string(filepath.Separator)) {
+		return nil, fmt.Errorf("overlay: seed %s is outside of %s", lower, v.seedDirPath)
+	}
+	if strings.ContainsAny(seedPath, invalidPathChars) {
+		return nil, fmt.Errorf("overlay: seed path cannot contain any of %q: %s", invalidPathChars, seedPath)
+	}
+	fileInfo, err := os.Stat(seedPath)
+	if err != nil {
+		return nil, err
+	}
+	if !fileInfo.IsDir() {
+		return nil, fmt.Errorf("overlay: seed %s is not a directory", lower)
+	}
+	return []string{seedPath}, nil
+}
+
+// getDependents returns the volumes that use the volume with the given name as a lower layer.
+func (v *volumeDriver) getDependents(name string) ([]string, error) {
+	names, err := v.ListVolumeNames()
+	if err != nil {
+		return nil, err
+	}
+	upperDirPath := filepath.Join(v.getDirPath(name), upperDirName)
+	var dependents []string
+	for _, otherName := range names {
+		if otherName == name {
+			continue
+		}
+		lowers, err := readLowers(v.getDirPath(otherName))
+		if err != nil {
+			// a volume being created does not have its lowers yet
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, lower := range lowers {
+			if lower == upperDirPath {
+				dependents = append(dependents, otherName)
+				break
+			}
+		}
+	}
+	return dependents, nil
+}
+
+func (v *volumeDriver) getDirPath(name string) string {
+	return filepath.Join(v.volumesDirPath, name)
+}
+
+func readLowers(dirPath string) ([]string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dirPath, lowersFileName))
+	if err != nil {
+		return nil, err
+	}
+	lowers := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lowers) == 0 || lowers[0] == "" {
+		return nil, errors.New("overlay: no lower directories")
+	}
+	return lowers, nil
+}
+
+// checkName errors if the name is not a single path element that can be in the mount options.
+func checkName(name string) error {
+	if err := internal.CheckName("overlay", name); err != nil {
+		return err
+	}
+	if strings.ContainsAny(name, invalidPathChars) {
+		return fmt.Errorf("overlay: invalid volume name: %q", name)
+	}
+	return nil
+}
+
+func parseLower(opts pkgmap.StringStringMap) (string, error) {
+	var lower string
+	for key, value := range opts {
+		switch key {
+		case OptLower:
+			lower = value
+		default:
+			return "", fmt.Errorf("overlay: unknown opt: %s", key)
+		}
+	}
+	if lower == "" {
+		return "", fmt.Errorf("overlay: the %s opt is required", OptLower)
+	}
+	return lower, nil
+}
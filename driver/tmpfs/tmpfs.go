@@ -0,0 +1,38 @@
+/*
+Package tmpfs implements a dockervolume.VolumeDriver for ephemeral volumes in memory, backed by tmpfs.
+
+A tmpfs is mounted on Mount and torn down on Unmount, so the data of a
+volume does not survive its last unmount.
+
+The opts of a volume are:
+
+	size  the maximum size of the tmpfs, in bytes, with a k, m or g suffix, or a percentage of the memory with a % suffix, by default half of the memory
+	mode  the permissions of the root of the tmpfs in octal, by default 1777
+	uid   the user id of the owner of the root of the tmpfs, by default root
+	gid   the group id of the owner of the root of the tmpfs, by default root
+
+Any other opt is an error. Mounting volumes is only supported on Linux, and requires root.
+*/
+package tmpfs // import "go.pedge.io/dockervolume/driver/tmpfs"
+
+import (
+	"go.pedge.io/dockervolume"
+)
+
+const (
+	// OptSize is the opt for the maximum size of the tmpfs.
+	OptSize = "size"
+	// OptMode is the opt for the permissions of the root of the tmpfs, in octal.
+	OptMode = "mode"
+	// OptUID is the opt for the user id of the owner of the root of the tmpfs.
+	OptUID = "uid"
+	// OptGID is the opt for the group id of the owner of the root of the tmpfs.
+	OptGID = "gid"
+)
+
+// NewVolumeDriver returns a new dockervolume.VolumeDriver that mounts the
+// volumes in directories named after the volumes under baseDirPath.
+// baseDirPath is created if it does not exist.
+func NewVolumeDriver(baseDirPath string) (dockervolume.VolumeDriver, error) {
+	return newVolumeDriver(baseDirPath)
+}
@@ -0,0 +1,84 @@
+package tmpfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.pedge.io/dockervolume"
+	"go.pedge.io/dockervolume/dockervolumetest"
+)
+
+func TestSuite(t *testing.T) {
+	dockervolumetest.RequireRoot(t)
+	dockervolumetest.RunSuite(
+		t,
+		func(dirPath string) (dockervolume.VolumeDriver, error) {
+			return NewVolumeDriver(dirPath)
+		},
+		dockervolumetest.SuiteOptions{
+			Opts: map[string]string{OptSize: "1m"},
+		},
+	)
+}
+
+func TestMount(t *testing.T) {
+	dockervolumetest.RequireRoot(t)
+	dirPath, err := ioutil.TempDir("", "tmpfs")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dirPath) }()
+	volumeDriver, err := NewVolumeDriver(dirPath)
+	require.NoError(t, err)
+	opts := map[string]string{OptSize: "1m", OptMode: "0750", OptUID: "1234", OptGID: "5678"}
+	require.NoError(t, volumeDriver.Create("foo", opts))
+	mountpoint, err := volumeDriver.Mount("foo", opts)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dirPath, "foo"), mountpoint)
+	fileInfo, err := os.Stat(mountpoint)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0750), fileInfo.Mode().Perm())
+	stat := fileInfo.Sys().(*syscall.Stat_t)
+	require.Equal(t, uint32(1234), stat.Uid)
+	require.Equal(t, uint32(5678), stat.Gid)
+	statfs := &syscall.Statfs_t{}
+	require.NoError(t, syscall.Statfs(mountpoint, statfs))
+	require.Equal(t, uint64(1<<20), statfs.Blocks*uint64(statfs.Bsize))
+	require.Error(t, ioutil.WriteFile(filepath.Join(mountpoint, "big"), make([]byte, 2<<20), 0644))
+	require.NoError(t, volumeDriver.Unmount("foo", opts, mountpoint))
+	_, err = os.Stat(mountpoint)
+	require.True(t, os.IsNotExist(err))
+	require.NoError(t, volumeDriver.Remove("foo", opts, ""))
+}
+
+func TestOpts(t *testing.T) {
+	volumeDriver, err := NewVolumeDriver(os.TempDir())
+	require.NoError(t, err)
+	for _, opts := range []map[string]string{
+		nil,
+		{OptSize: "1024"},
+		{OptSize: "16m", OptMode: "1777", OptUID: "0", OptGID: "0"},
+		{OptSize: "50%"},
+	} {
+		require.NoError(t, volumeDriver.Create("foo", opts), "%v", opts)
+	}
+	for _, opts := range []map[string]string{
+		{OptSize: "0"},
+		{OptSize: "1t"},
+		{OptSize: "1m,exec"},
+		{OptMode: "0999"},
+		{OptUID: "-1"},
+		{OptGID: "root"},
+		{"exec": ""},
+	} {
+		require.Error(t, volumeDriver.Create("foo", opts), "%v", opts)
+	}
+	for _, name := range []string{"", "..", "foo/bar"} {
+		require.Error(t, volumeDriver.Create(name, nil), "%q", name)
+	}
+	mountOptions, err := getMountOptions(map[string]string{OptUID: "1", OptSize: "1m", OptMode: "0700"})
+	require.NoError(t, err)
+	require.Equal(t, "mode=0700,size=1m,uid=1", mountOptions)
+}
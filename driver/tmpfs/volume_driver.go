@@ -0,0 +1,96 @@
+package tmpfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.pedge.io/dockervolume/driver/internal"
+	"go.pedge.io/pkg/map"
+)
+
+var (
+	sizeRegexp = regexp.MustCompile(`^[1-9][0-9]*[kKmMgG%]?$`)
+)
+
+type volumeDriver struct {
+	baseDirPath string
+}
+
+func newVolumeDriver(baseDirPath string) (*volumeDriver, error) {
+	if err := os.MkdirAll(baseDirPath, 0755); err != nil {
+		return nil, err
+	}
+	return &volumeDriver{baseDirPath}, nil
+}
+
+func (v *volumeDriver) Create(name string, opts pkgmap.StringStringMap) error {
+	if err := internal.CheckName("tmpfs", name); err != nil {
+		return err
+	}
+	_, err := getMountOptions(opts)
+	return err
+}
+
+func (v *volumeDriver) Remove(name string, _ pkgmap.StringStringMap, _ string) error {
+	return internal.CheckName("tmpfs", name)
+}
+
+func (v *volumeDriver) Mount(name string, opts pkgmap.StringStringMap) (string, error) {
+	if err := internal.CheckName("tmpfs", name); err != nil {
+		return "", err
+	}
+	mountOptions, err := getMountOptions(opts)
+	if err != nil {
+		return "", err
+	}
+	mountpoint := filepath.Join(v.baseDirPath, name)
+	if err := os.MkdirAll(mountpoint, 0755); err != nil {
+		return "", err
+	}
+	if err := internal.Mount("tmpfs", mountpoint, "tmpfs", internal.MountNoDev|internal.MountNoSuid, mountOptions); err != nil {
+		_ = os.Remove(mountpoint)
+		return "", err
+	}
+	return mountpoint, nil
+}
+
+func (v *volumeDriver) Unmount(name string, _ pkgmap.StringStringMap, mountpoint string) error {
+	if err := internal.CheckName("tmpfs", name); err != nil {
+		return err
+	}
+	if err := internal.Unmount(mountpoint); err != nil {
+		return err
+	}
+	return os.Remove(mountpoint)
+}
+
+// getMountOptions validates the opts and returns the tmpfs mount options for them.
+func getMountOptions(opts pkgmap.StringStringMap) (string, error) {
+	var mountOptions []string
+	for key, value := range opts {
+		switch key {
+		case OptSize:
+			if !sizeRegexp.MatchString(value) {
+				return "", fmt.Errorf("tmpfs: invalid %s, must be a number with an optional k, m, g or %% suffix: %s", key, value)
+			}
+		case OptMode:
+			if mode, err := strconv.ParseUint(value, 8, 32); err != nil || mode > 07777 {
+				return "", fmt.Errorf("tmpfs: invalid %s, must be octal permissions: %s", key, value)
+			}
+		case OptUID, OptGID:
+			if _, err := strconv.ParseUint(value, 10, 31); err != nil {
+				return "", fmt.Errorf("tmpfs: invalid %s: %s", key, value)
+			}
+		default:
+			return "", fmt.Errorf("tmpfs: unknown opt: %s", key)
+		}
+		mountOptions = append(mountOptions, key+"="+value)
+	}
+	sort.Strings(mountOptions)
+	return strings.Join(mountOptions, ","), nil
+}
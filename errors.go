@@ -0,0 +1,114 @@
+package dockervolume
+
+import (
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	volumeErrorPrefix = "dockervolume: "
+	driverErrorPrefix = "driver error: "
+)
+
+var (
+	sentinelErrors = []error{
+		ErrVolumeNotFound,
+		ErrVolumeExists,
+		ErrAlreadyMounted,
+		ErrNotMounted,
+		ErrUnimplemented,
+	}
+)
+
+func newVolumeError(op string, name string, err error) error {
+	return &VolumeError{
+		Op:   op,
+		Name: name,
+		Err:  err,
+	}
+}
+
+func newDriverError(op string, name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return newVolumeError(op, name, &DriverError{err})
+}
+
+// errorFromString reconstructs an error created with newVolumeError from
+// its string, so that errors.Is and errors.As work on the client side.
+//
+// The string is "dockervolume: op name: err". Errors that are not about a
+// single volume are only the err part.
+func errorFromString(s string) error {
+	if strings.HasPrefix(s, driverErrorPrefix) {
+		return &DriverError{errors.New(strings.TrimPrefix(s, driverErrorPrefix))}
+	}
+	for _, sentinelError := range sentinelErrors {
+		if s == sentinelError.Error() {
+			return sentinelError
+		}
+	}
+	if !strings.HasPrefix(s, volumeErrorPrefix) {
+		return errors.New(s)
+	}
+	rest := strings.TrimPrefix(s, volumeErrorPrefix)
+	spaceIndex := strings.Index(rest, " ")
+	if spaceIndex < 0 {
+		return errors.New(s)
+	}
+	op := rest[:spaceIndex]
+	rest = rest[spaceIndex+1:]
+	if i := strings.Index(rest, ": "+driverErrorPrefix); i >= 0 {
+		return newDriverError(op, rest[:i], errors.New(rest[i+len(": "+driverErrorPrefix):]))
+	}
+	for _, sentinelError := range sentinelErrors {
+		if suffix := ": " + sentinelError.Error(); strings.HasSuffix(rest, suffix) {
+			return newVolumeError(op, strings.TrimSuffix(rest, suffix), sentinelError)
+		}
+	}
+	return errors.New(s)
+}
+
+func toGRPCError(err error) error {
+	if err == nil {
+		return nil
+	}
+	code := codes.Unknown
+	var driverError *DriverError
+	switch {
+	case errors.Is(err, ErrVolumeNotFound):
+		code = codes.NotFound
+	case errors.Is(err, ErrVolumeExists):
+		code = codes.AlreadyExists
+	case errors.Is(err, ErrAlreadyMounted), errors.Is(err, ErrNotMounted):
+		code = codes.FailedPrecondition
+	case errors.Is(err, ErrUnimplemented):
+		code = codes.Unimplemented
+	case errors.As(err, &driverError):
+		code = codes.Internal
+	}
+	return grpc.Errorf(code, "%s", err.Error())
+}
+
+// fromGRPCError reconstructs an error created with toGRPCError.
+func fromGRPCError(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch grpc.Code(err) {
+	case codes.NotFound, codes.AlreadyExists, codes.FailedPrecondition, codes.Internal:
+		return errorFromString(grpc.ErrorDesc(err))
+	case codes.Unimplemented:
+		// grpc also returns Unimplemented for servers that do not know a method
+		if desc := grpc.ErrorDesc(err); desc == ErrUnimplemented.Error() || strings.HasPrefix(desc, volumeErrorPrefix) {
+			return errorFromString(desc)
+		}
+		return err
+	default:
+		return err
+	}
+}
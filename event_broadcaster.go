@@ -0,0 +1,64 @@
+package dockervolume
+
+import (
+	"sync"
+)
+
+// eventBroadcaster sends events to every subscriber without ever blocking the
+// publisher. A subscriber whose buffer is full is dropped, and its channel closed.
+type eventBroadcaster struct {
+	bufferSize     int
+	nextID         int
+	idToSubscriber map[int]chan *Event
+	lock           *sync.Mutex
+}
+
+func newEventBroadcaster(bufferSize int) *eventBroadcaster {
+	return &eventBroadcaster{
+		bufferSize,
+		0,
+		make(map[int]chan *Event),
+		&sync.Mutex{},
+	}
+}
+
+// subscribe returns a channel of the events published from now on, and a
+// function to call once the events are not needed anymore.
+func (e *eventBroadcaster) subscribe() (<-chan *Event, func()) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	id := e.nextID
+	e.nextID++
+	subscriber := make(chan *Event, e.bufferSize)
+	e.idToSubscriber[id] = subscriber
+	return subscriber, func() { e.unsubscribe(id) }
+}
+
+func (e *eventBroadcaster) unsubscribe(id int) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if subscriber, ok := e.idToSubscriber[id]; ok {
+		close(subscriber)
+		delete(e.idToSubscriber, id)
+	}
+}
+
+// publish sends the event to every subscriber. The event must not be modified afterwards.
+func (e *eventBroadcaster) publish(event *Event) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for id, subscriber := range e.idToSubscriber {
+		select {
+		case subscriber <- event:
+		default:
+			close(subscriber)
+			delete(e.idToSubscriber, id)
+		}
+	}
+}
+
+func (e *eventBroadcaster) numSubscribers() int {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return len(e.idToSubscriber)
+}
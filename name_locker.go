@@ -0,0 +1,53 @@
+package dockervolume
+
+import (
+	"sync"
+)
+
+// nameLocker is a set of mutexes keyed by name. Mutexes are created on demand
+// and discarded once nobody holds or waits for them.
+type nameLocker struct {
+	nameToEntry map[string]*nameLockerEntry
+	lock        *sync.Mutex
+}
+
+type nameLockerEntry struct {
+	lock     *sync.Mutex
+	refCount int
+}
+
+func newNameLocker() *nameLocker {
+	return &nameLocker{
+		make(map[string]*nameLockerEntry),
+		&sync.Mutex{},
+	}
+}
+
+func (n *nameLocker) Lock(name string) {
+	n.lock.Lock()
+	entry, ok := n.nameToEntry[name]
+	if !ok {
+		entry = &nameLockerEntry{
+			&sync.Mutex{},
+			0,
+		}
+		n.nameToEntry[name] = entry
+	}
+	entry.refCount++
+	n.lock.Unlock()
+	entry.lock.Lock()
+}
+
+func (n *nameLocker) Unlock(name string) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	entry, ok := n.nameToEntry[name]
+	if !ok {
+		panic("dockervolume: unlock of unlocked name: " + name)
+	}
+	entry.lock.Unlock()
+	entry.refCount--
+	if entry.refCount == 0 {
+		delete(n.nameToEntry, name)
+	}
+}
@@ -0,0 +1,288 @@
+package dockervolume
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	pluginContentType = "application/vnd.docker.plugins.v1+json"
+	// pluginUnixHost is the host used in URLs for plugins on unix sockets,
+	// the socket is dialed instead.
+	pluginUnixHost       = "plugin"
+	defaultPluginTimeout = 30 * time.Second
+)
+
+type pluginClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func newPluginClient(address string, opts PluginClientOptions) (*pluginClient, error) {
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = defaultPluginTimeout
+	}
+	var network string
+	var dialAddress string
+	var baseURL string
+	switch {
+	case strings.HasPrefix(address, "unix://"):
+		network = "unix"
+		dialAddress = strings.TrimPrefix(address, "unix://")
+		baseURL = "http://" + pluginUnixHost
+	case strings.HasPrefix(address, "tcp://"):
+		network = "tcp"
+		dialAddress = strings.TrimPrefix(address, "tcp://")
+		baseURL = "http://" + dialAddress
+	default:
+		return nil, fmt.Errorf("dockervolume: invalid plugin address, must start with unix:// or tcp://: %s", address)
+	}
+	if dialAddress == "" {
+		return nil, fmt.Errorf("dockervolume: invalid plugin address: %s", address)
+	}
+	return &pluginClient{
+		baseURL,
+		&http.Client{
+			Transport: &http.Transport{
+				Dial: func(string, string) (net.Conn, error) {
+					return net.DialTimeout(network, dialAddress, timeout)
+				},
+			},
+			Timeout: timeout,
+		},
+	}, nil
+}
+
+func (p *pluginClient) Activate() ([]string, error) {
+	response := &pluginActivateResponse{}
+	if err := p.call("/Plugin.Activate", nil, response); err != nil {
+		return nil, err
+	}
+	return response.Implements, nil
+}
+
+func (p *pluginClient) Create(name string, opts map[string]string) error {
+	response := &pluginErrResponse{}
+	if err := p.call("/VolumeDriver.Create", &pluginRequest{Name: name, Opts: opts}, response); err != nil {
+		return err
+	}
+	return pluginError(response.Err)
+}
+
+func (p *pluginClient) Remove(name string) error {
+	response := &pluginErrResponse{}
+	if err := p.call("/VolumeDriver.Remove", &pluginRequest{Name: name}, response); err != nil {
+		return err
+	}
+	return pluginError(response.Err)
+}
+
+func (p *pluginClient) Path(name string) (string, error) {
+	response := &pluginMountpointResponse{}
+	if err := p.call("/VolumeDriver.Path", &pluginRequest{Name: name}, response); err != nil {
+		return "", err
+	}
+	if err := pluginError(response.Err); err != nil {
+		return "", err
+	}
+	return response.Mountpoint, nil
+}
+
+func (p *pluginClient) Mount(name string, id string) (string, error) {
+	response := &pluginMountpointResponse{}
+	if err := p.call("/VolumeDriver.Mount", &pluginRequest{Name: name, ID: id}, response); err != nil {
+		return "", err
+	}
+	if err := pluginError(response.Err); err != nil {
+		return "", err
+	}
+	return response.Mountpoint, nil
+}
+
+func (p *pluginClient) Unmount(name string, id string) error {
+	response := &pluginErrResponse{}
+	if err := p.call("/VolumeDriver.Unmount", &pluginRequest{Name: name, ID: id}, response); err != nil {
+		return err
+	}
+	return pluginError(response.Err)
+}
+
+func (p *pluginClient) Get(name string) (*Volume, error) {
+	response := &pluginVolumeResponse{}
+	if err := p.call("/VolumeDriver.Get", &pluginRequest{Name: name}, response); err != nil {
+		return nil, err
+	}
+	if err := pluginError(response.Err); err != nil {
+		return nil, err
+	}
+	if response.Volume == nil {
+		return nil, fmt.Errorf("dockervolume: plugin returned no volume for %s", name)
+	}
+	return response.Volume.toVolume(), nil
+}
+
+func (p *pluginClient) List() ([]*Volume, error) {
+	response := &pluginVolumesResponse{}
+	if err := p.call("/VolumeDriver.List", &pluginRequest{}, response); err != nil {
+		return nil, err
+	}
+	if err := pluginError(response.Err); err != nil {
+		return nil, err
+	}
+	volumes := make([]*Volume, len(response.Volumes))
+	for i, volume := range response.Volumes {
+		volumes[i] = volume.toVolume()
+	}
+	return volumes, nil
+}
+
+func (p *pluginClient) Capabilities() (*Capabilities, error) {
+	response := &pluginCapabilitiesResponse{}
+	if err := p.call("/VolumeDriver.Capabilities", &pluginRequest{}, response); err != nil {
+		// plugins that predate capabilities do not have the endpoint, and are local
+		var statusError *pluginStatusError
+		if errors.As(err, &statusError) && statusError.StatusCode == http.StatusNotFound {
+			return &Capabilities{
+				Scope: ScopeLocal,
+			}, nil
+		}
+		return nil, err
+	}
+	if response.Capabilities.Scope == "" {
+		response.Capabilities.Scope = ScopeLocal
+	}
+	return &Capabilities{
+		Scope: response.Capabilities.Scope,
+	}, nil
+}
+
+// call posts request as JSON to path and decodes the JSON response into response.
+func (p *pluginClient) call(path string, request interface{}, response interface{}) error {
+	body := &bytes.Buffer{}
+	if request != nil {
+		if err := json.NewEncoder(body).Encode(request); err != nil {
+			return err
+		}
+	}
+	httpRequest, err := http.NewRequest("POST", p.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	httpRequest.Header.Set("Accept", pluginContentType)
+	httpRequest.Header.Set("Content-Type", pluginContentType)
+	httpResponse, err := p.httpClient.Do(httpRequest)
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+	data, err := ioutil.ReadAll(httpResponse.Body)
+	if err != nil {
+		return err
+	}
+	if httpResponse.StatusCode != http.StatusOK {
+		// plugins can also report errors with a status code and a body with Err
+		errResponse := &pluginErrResponse{}
+		if json.Unmarshal(data, errResponse) == nil && errResponse.Err != "" {
+			return pluginError(errResponse.Err)
+		}
+		return &pluginStatusError{path, httpResponse.StatusCode, strings.TrimSpace(string(data))}
+	}
+	if err := json.Unmarshal(data, response); err != nil {
+		return fmt.Errorf("dockervolume: invalid response for plugin call %s: %s", path, err.Error())
+	}
+	return nil
+}
+
+// pluginError returns the error for the Err of a response, reconstructing
+// the errors of this package for plugins based on it.
+func pluginError(s string) error {
+	if s == "" {
+		return nil
+	}
+	return errorFromString(s)
+}
+
+// pluginStatusError is the error for a plugin call that failed with a status
+// code and without an Err.
+type pluginStatusError struct {
+	Path       string
+	StatusCode int
+	Body       string
+}
+
+func (p *pluginStatusError) Error() string {
+	return fmt.Sprintf("dockervolume: plugin call %s failed with status %d: %s", p.Path, p.StatusCode, p.Body)
+}
+
+type pluginRequest struct {
+	Name string            `json:",omitempty"`
+	Opts map[string]string `json:",omitempty"`
+	ID   string            `json:",omitempty"`
+}
+
+type pluginActivateResponse struct {
+	Implements []string
+}
+
+type pluginErrResponse struct {
+	Err string
+}
+
+type pluginMountpointResponse struct {
+	Mountpoint string
+	Err        string
+}
+
+type pluginVolume struct {
+	Name       string
+	Mountpoint string
+	// CreatedAt is in RFC 3339 format.
+	CreatedAt string
+	Status    map[string]interface{}
+}
+
+func (p *pluginVolume) toVolume() *Volume {
+	volume := &Volume{
+		Name:       p.Name,
+		Mountpoint: p.Mountpoint,
+	}
+	if createdAt, err := time.Parse(time.RFC3339Nano, p.CreatedAt); err == nil {
+		volume.CreatedAt = timeToTimestamp(createdAt)
+	}
+	if len(p.Status) > 0 {
+		volume.Status = make(map[string]string, len(p.Status))
+		for key, value := range p.Status {
+			if s, ok := value.(string); ok {
+				volume.Status[key] = s
+			} else {
+				data, _ := json.Marshal(value)
+				volume.Status[key] = string(data)
+			}
+		}
+	}
+	return volume
+}
+
+type pluginVolumeResponse struct {
+	Volume *pluginVolume
+	Err    string
+}
+
+type pluginVolumesResponse struct {
+	Volumes []*pluginVolume
+	Err     string
+}
+
+type pluginCapabilitiesResponse struct {
+	Capabilities struct {
+		Scope string
+	}
+}
@@ -1,8 +1,6 @@
 package dockervolume
 
 import (
-	"errors"
-
 	"google.golang.org/grpc"
 
 	"go.pedge.io/google-protobuf"
@@ -30,43 +28,175 @@ func (v *volumeDriverClient) Path(name string) (string, error) {
 	return callNameToMountpointErr(name, v.apiClient.Path)
 }
 
-func (v *volumeDriverClient) Mount(name string) (string, error) {
-	return callNameToMountpointErr(name, v.apiClient.Mount)
+func (v *volumeDriverClient) Mount(name string, id string) (string, error) {
+	return callNameIDToMountpointErr(name, id, v.apiClient.Mount)
 }
 
-func (v *volumeDriverClient) Unmount(name string) error {
-	return callNameToErr(name, v.apiClient.Unmount)
+func (v *volumeDriverClient) Unmount(name string, id string) error {
+	return callNameIDToErr(name, id, v.apiClient.Unmount)
 }
 
-func (v *volumeDriverClient) Cleanup() ([]*Volume, error) {
-	response, err := v.apiClient.Cleanup(
+func (v *volumeDriverClient) Get(name string) (*Volume, error) {
+	response, err := v.apiClient.Get(
+		context.Background(),
+		&NameRequest{
+			Name: name,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if response.Err != "" {
+		return nil, errorFromString(response.Err)
+	}
+	return fromPluginVolume(response.Volume)
+}
+
+func (v *volumeDriverClient) List() ([]*Volume, error) {
+	response, err := v.apiClient.List(
+		context.Background(),
+		google_protobuf.EmptyInstance,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if response.Err != "" {
+		return nil, errorFromString(response.Err)
+	}
+	return fromPluginVolumes(response.Volumes)
+}
+
+func (v *volumeDriverClient) Capabilities() (*Capabilities, error) {
+	response, err := v.apiClient.Capabilities(
 		context.Background(),
 		google_protobuf.EmptyInstance,
 	)
 	if err != nil {
 		return nil, err
 	}
+	return response.Capabilities, nil
+}
+
+func (v *volumeDriverClient) Cleanup() ([]*Volume, error) {
+	return v.CleanupVolumes(&CleanupRequest{})
+}
+
+func (v *volumeDriverClient) CleanupVolumes(request *CleanupRequest) ([]*Volume, error) {
+	response, err := v.apiClient.Cleanup(
+		context.Background(),
+		request,
+	)
+	if err != nil {
+		return nil, fromGRPCError(err)
+	}
 	return response.Volume, nil
 }
 
 func (v *volumeDriverClient) GetVolume(name string) (*Volume, error) {
-	return v.apiClient.GetVolume(
+	volume, err := v.apiClient.GetVolume(
 		context.Background(),
 		&NameRequest{
 			Name: name,
 		},
 	)
+	if err != nil {
+		return nil, fromGRPCError(err)
+	}
+	return volume, nil
 }
 
 func (v *volumeDriverClient) ListVolumes() ([]*Volume, error) {
+	response, err := v.ListVolumesPage(&ListVolumesRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return response.Volume, nil
+}
+
+func (v *volumeDriverClient) ListVolumesPage(request *ListVolumesRequest) (*Volumes, error) {
 	response, err := v.apiClient.ListVolumes(
+		context.Background(),
+		request,
+	)
+	if err != nil {
+		return nil, fromGRPCError(err)
+	}
+	return response, nil
+}
+
+func (v *volumeDriverClient) Reconcile(repair bool) (*ReconcileResponse, error) {
+	response, err := v.apiClient.Reconcile(
+		context.Background(),
+		&ReconcileRequest{
+			Repair: repair,
+		},
+	)
+	if err != nil {
+		return nil, fromGRPCError(err)
+	}
+	return response, nil
+}
+
+func (v *volumeDriverClient) ListDriverVolumes() ([]string, error) {
+	response, err := v.apiClient.ListDriverVolumes(
 		context.Background(),
 		google_protobuf.EmptyInstance,
 	)
 	if err != nil {
-		return nil, err
+		return nil, fromGRPCError(err)
+	}
+	return response.Name, nil
+}
+
+func (v *volumeDriverClient) GetVolumeStatus(name string) (map[string]string, error) {
+	response, err := v.apiClient.GetVolumeStatus(
+		context.Background(),
+		&NameRequest{
+			Name: name,
+		},
+	)
+	if err != nil {
+		return nil, fromGRPCError(err)
+	}
+	return response.Status, nil
+}
+
+func (v *volumeDriverClient) SnapshotVolume(name string, snapshotName string) error {
+	_, err := v.apiClient.SnapshotVolume(
+		context.Background(),
+		&SnapshotRequest{
+			Name:         name,
+			SnapshotName: snapshotName,
+		},
+	)
+	return fromGRPCError(err)
+}
+
+func (v *volumeDriverClient) ResizeVolume(name string, sizeBytes uint64) error {
+	_, err := v.apiClient.ResizeVolume(
+		context.Background(),
+		&ResizeRequest{
+			Name:      name,
+			SizeBytes: sizeBytes,
+		},
+	)
+	return fromGRPCError(err)
+}
+
+func (v *volumeDriverClient) Watch(ctx context.Context, eventFunc func(*Event) error) error {
+	watchClient, err := v.apiClient.Watch(ctx, google_protobuf.EmptyInstance)
+	if err != nil {
+		return err
+	}
+	for {
+		event, err := watchClient.Recv()
+		if err != nil {
+			return err
+		}
+		if err := eventFunc(event); err != nil {
+			return err
+		}
 	}
-	return response.Volume, nil
 }
 
 func callNameOptsToErr(
@@ -85,7 +215,7 @@ func callNameOptsToErr(
 		return err
 	}
 	if response.Err != "" {
-		return errors.New(response.Err)
+		return errorFromString(response.Err)
 	}
 	return nil
 }
@@ -104,7 +234,7 @@ func callNameToErr(
 		return err
 	}
 	if response.Err != "" {
-		return errors.New(response.Err)
+		return errorFromString(response.Err)
 	}
 	return nil
 }
@@ -120,10 +250,52 @@ func callNameToMountpointErr(
 		},
 	)
 	if err != nil {
-		return response.Mountpoint, err
+		return "", err
+	}
+	if response.Err != "" {
+		return response.Mountpoint, errorFromString(response.Err)
+	}
+	return response.Mountpoint, nil
+}
+
+func callNameIDToErr(
+	name string,
+	id string,
+	f func(context.Context, *NameIDRequest, ...grpc.CallOption) (*ErrResponse, error),
+) error {
+	response, err := f(
+		context.Background(),
+		&NameIDRequest{
+			Name: name,
+			Id:   id,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if response.Err != "" {
+		return errorFromString(response.Err)
+	}
+	return nil
+}
+
+func callNameIDToMountpointErr(
+	name string,
+	id string,
+	f func(context.Context, *NameIDRequest, ...grpc.CallOption) (*MountpointErrResponse, error),
+) (string, error) {
+	response, err := f(
+		context.Background(),
+		&NameIDRequest{
+			Name: name,
+			Id:   id,
+		},
+	)
+	if err != nil {
+		return "", err
 	}
 	if response.Err != "" {
-		return response.Mountpoint, errors.New(response.Err)
+		return response.Mountpoint, errorFromString(response.Err)
 	}
 	return response.Mountpoint, nil
 }
@@ -0,0 +1,84 @@
+package dockervolume
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// volumeFilter matches the volumes of a ListVolumesRequest.
+type volumeFilter struct {
+	namePrefix string
+	// keyToValue has an empty value for a selector that is only a key.
+	keyToValue map[string]string
+	keyOnly    map[string]bool
+	mountState MountState
+}
+
+func newVolumeFilter(request *ListVolumesRequest) (*volumeFilter, error) {
+	keyToValue := make(map[string]string)
+	keyOnly := make(map[string]bool)
+	for _, selector := range request.Selector {
+		key, value, hasValue := selector, "", false
+		if i := strings.Index(selector, "="); i >= 0 {
+			key, value, hasValue = selector[:i], selector[i+1:], true
+		}
+		if key == "" {
+			return nil, fmt.Errorf("dockervolume: invalid selector: %s", selector)
+		}
+		// an opt only has one value, so a second selector for it could only be ignored
+		if _, ok := keyToValue[key]; ok {
+			return nil, fmt.Errorf("dockervolume: duplicate selector for key %s: %s", key, selector)
+		}
+		keyToValue[key] = value
+		if !hasValue {
+			keyOnly[key] = true
+		}
+	}
+	switch request.MountState {
+	case MountState_MOUNT_STATE_ANY, MountState_MOUNT_STATE_MOUNTED, MountState_MOUNT_STATE_UNMOUNTED:
+	default:
+		return nil, fmt.Errorf("dockervolume: invalid mount state: %v", request.MountState)
+	}
+	return &volumeFilter{
+		request.NamePrefix,
+		keyToValue,
+		keyOnly,
+		request.MountState,
+	}, nil
+}
+
+func (f *volumeFilter) matches(volume *Volume) bool {
+	if !strings.HasPrefix(volume.Name, f.namePrefix) {
+		return false
+	}
+	for key, value := range f.keyToValue {
+		optValue, ok := volume.Opts[key]
+		if !ok || (!f.keyOnly[key] && optValue != value) {
+			return false
+		}
+	}
+	switch f.mountState {
+	case MountState_MOUNT_STATE_MOUNTED:
+		return len(volume.MountIds) > 0
+	case MountState_MOUNT_STATE_UNMOUNTED:
+		return len(volume.MountIds) == 0
+	default:
+		return true
+	}
+}
+
+// A page token is the name of the last volume of the previous page, so that
+// pages stay consistent while volumes are created and removed.
+
+func encodePageToken(name string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(name))
+}
+
+func decodePageToken(pageToken string) (string, error) {
+	name, err := base64.RawURLEncoding.DecodeString(pageToken)
+	if err != nil {
+		return "", fmt.Errorf("dockervolume: invalid page token: %s", pageToken)
+	}
+	return string(name), nil
+}
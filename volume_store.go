@@ -0,0 +1,159 @@
+package dockervolume
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/jsonpb"
+)
+
+const (
+	fileVolumeStoreExtension  = ".json"
+	fileVolumeStoreTempPrefix = ".tmp-"
+)
+
+var (
+	fileVolumeStoreMarshaler = &jsonpb.Marshaler{
+		Indent: "  ",
+	}
+)
+
+type memoryVolumeStore struct {
+	nameToVolume map[string]*Volume
+	lock         *sync.RWMutex
+}
+
+func newMemoryVolumeStore() *memoryVolumeStore {
+	return &memoryVolumeStore{
+		make(map[string]*Volume),
+		&sync.RWMutex{},
+	}
+}
+
+func (m *memoryVolumeStore) Put(volume *Volume) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.nameToVolume[volume.Name] = copyVolume(volume)
+	return nil
+}
+
+func (m *memoryVolumeStore) Delete(name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.nameToVolume, name)
+	return nil
+}
+
+func (m *memoryVolumeStore) List() ([]*Volume, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	volumes := make([]*Volume, 0, len(m.nameToVolume))
+	for _, volume := range m.nameToVolume {
+		volumes = append(volumes, copyVolume(volume))
+	}
+	return volumes, nil
+}
+
+type fileVolumeStore struct {
+	dirPath string
+	lock    *sync.Mutex
+}
+
+func newFileVolumeStore(dirPath string) (*fileVolumeStore, error) {
+	if err := os.MkdirAll(dirPath, 0700); err != nil {
+		return nil, err
+	}
+	// temporary files are left behind by a crash during Put
+	tempFilePaths, err := filepath.Glob(filepath.Join(dirPath, fileVolumeStoreTempPrefix+"*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, tempFilePath := range tempFilePaths {
+		if err := os.Remove(tempFilePath); err != nil {
+			return nil, err
+		}
+	}
+	return &fileVolumeStore{
+		dirPath,
+		&sync.Mutex{},
+	}, nil
+}
+
+func (f *fileVolumeStore) Put(volume *Volume) error {
+	buffer := bytes.NewBuffer(nil)
+	if err := fileVolumeStoreMarshaler.Marshal(buffer, volume); err != nil {
+		return err
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	// write to a temporary file and rename so that a crash never leaves a partially written volume
+	file, err := ioutil.TempFile(f.dirPath, fileVolumeStoreTempPrefix)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(buffer.Bytes()); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), f.filePath(volume.Name))
+}
+
+func (f *fileVolumeStore) Delete(name string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if err := os.Remove(f.filePath(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (f *fileVolumeStore) List() ([]*Volume, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	fileInfos, err := ioutil.ReadDir(f.dirPath)
+	if err != nil {
+		return nil, err
+	}
+	var volumes []*Volume
+	for _, fileInfo := range fileInfos {
+		if fileInfo.IsDir() || !strings.HasSuffix(fileInfo.Name(), fileVolumeStoreExtension) {
+			continue
+		}
+		filePath := filepath.Join(f.dirPath, fileInfo.Name())
+		file, err := os.Open(filePath)
+		if err != nil {
+			return nil, err
+		}
+		volume := &Volume{}
+		err = jsonpb.Unmarshal(file, volume)
+		_ = file.Close()
+		// one corrupt file does not make every other volume unavailable
+		if err != nil {
+			log.Printf("dockervolume: skipping invalid volume file %s: %s", filePath, err.Error())
+			continue
+		}
+		volumes = append(volumes, volume)
+	}
+	return volumes, nil
+}
+
+func (f *fileVolumeStore) filePath(name string) string {
+	// volume names are escaped so that they can never refer to a path outside of dirPath
+	return filepath.Join(f.dirPath, url.QueryEscape(name)+fileVolumeStoreExtension)
+}